@@ -0,0 +1,204 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tiggoins/function-checker/config"
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	"k8s.io/klog/v2"
+)
+
+// Env carries the cluster connection and the resources created for a run.
+type Env struct {
+	Config      config.CommandArg
+	Checker     *config.Checker
+	StatefulSet *resource.StatefulSet
+	Service     *resource.Service
+	// Headless is the headless service governing the statefulset.
+	Headless *resource.Service
+	// Ingress is nil when neither an ingressclass nor an ingress-nginx controller was found.
+	Ingress *resource.Ingress
+	// TLSSecret is nil unless the ingress is served over HTTPS with --ingress-tls.
+	TLSSecret *resource.TLSSecret
+	// DaemonSet is set by the node-health check when it is selected.
+	DaemonSet *resource.DaemonSet
+	// NodePort, LoadBalancer and ExternalName are set by the service checks of the same type
+	// when they are selected.
+	NodePort     *resource.Service
+	LoadBalancer *resource.Service
+	ExternalName *resource.Service
+	// ClientPod, DenyPolicy and AllowPolicy are set by the network-policy check when it is selected.
+	ClientPod   *resource.ClientPod
+	DenyPolicy  *resource.NetworkPolicy
+	AllowPolicy *resource.NetworkPolicy
+	// Resources holds every resource deleted in cleanup, checks add those they create while running.
+	Resources *resource.Operators
+}
+
+// Check verifies one functional area of the cluster.
+type Check interface {
+	Name() string
+	Description() string
+	// Run returns an error if the check failed, it may record evidence or skip itself through res.
+	Run(ctx context.Context, env *Env, res *report.Result) error
+}
+
+// Provisioner is implemented by checks which need resources besides the shared ones.
+// Provision builds them and records them in env, they are created along with the
+// shared resources and deleted in cleanup.
+type Provisioner interface {
+	Provision(env *Env) []resource.OperatorInterface
+}
+
+// LateProvisioner is implemented by checks which create resources while running, e.g.
+// because they would disturb the checks running before. LateProvision builds them and
+// records them in env, the check creates and deletes them itself.
+type LateProvisioner interface {
+	LateProvision(env *Env) []resource.OperatorInterface
+}
+
+// Provision collects the resources needed by the given checks.
+func Provision(env *Env, checks []Check) []resource.OperatorInterface {
+	var ops []resource.OperatorInterface
+	for _, c := range checks {
+		if p, ok := c.(Provisioner); ok {
+			ops = append(ops, p.Provision(env)...)
+		}
+	}
+	return ops
+}
+
+// LateProvision collects the resources the given checks create while running.
+func LateProvision(env *Env, checks []Check) []resource.OperatorInterface {
+	var ops []resource.OperatorInterface
+	for _, c := range checks {
+		if p, ok := c.(LateProvisioner); ok {
+			ops = append(ops, p.LateProvision(env)...)
+		}
+	}
+	return ops
+}
+
+var (
+	registry = map[string]Check{}
+	ordered  []Check
+)
+
+func init() {
+	// Checks run in this order. The pod network is verified before the services built on it,
+	// the network policies are removed again before the checks depending on traffic between
+	// pods, and storage-durability restarts the statefulset so it runs last.
+	for _, c := range []Check{
+		&nodeHealthCheck{},
+		&podNetworkCheck{},
+		&networkPolicyCheck{},
+		&serviceCheck{},
+		&headlessServiceCheck{},
+		&nodePortServiceCheck{},
+		&loadBalancerServiceCheck{},
+		&externalNameServiceCheck{},
+		&dnsCheck{},
+		&ingressCheck{},
+		&ingressTLSCheck{},
+		&storageCheck{},
+		&durabilityCheck{},
+	} {
+		Register(c)
+	}
+}
+
+// Register adds c to the registry, checks run in the order they are registered.
+func Register(c Check) {
+	if _, ok := registry[c.Name()]; ok {
+		panic(fmt.Sprintf("check [%s] registered twice", c.Name()))
+	}
+	registry[c.Name()] = c
+	ordered = append(ordered, c)
+}
+
+// All returns every registered check.
+func All() []Check {
+	return append([]Check(nil), ordered...)
+}
+
+// Select returns the registered checks named in enabled, or all of them if enabled is
+// empty, minus those named in skipped. Names may also be given comma separated.
+func Select(enabled, skipped []string) ([]Check, error) {
+	enabledSet, err := nameSet(enabled)
+	if err != nil {
+		return nil, err
+	}
+	skippedSet, err := nameSet(skipped)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []Check
+	for _, c := range ordered {
+		if len(enabledSet) > 0 && !enabledSet[c.Name()] {
+			continue
+		}
+		if skippedSet[c.Name()] {
+			continue
+		}
+		checks = append(checks, c)
+	}
+
+	return checks, nil
+}
+
+func nameSet(names []string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, name := range names {
+		for _, n := range strings.Split(name, ",") {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			if _, ok := registry[n]; !ok {
+				return nil, fmt.Errorf("unknown check [%s], use list-checks to see available checks", n)
+			}
+			set[n] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Run executes checks in order and records their results in rp.
+// Once ctx is done the remaining checks are recorded as skipped.
+func Run(ctx context.Context, env *Env, checks []Check, rp *report.Report) {
+	for i, c := range checks {
+		if ctx.Err() != nil {
+			Skip(checks[i:], rp, fmt.Sprintf("run was cancelled: %v", ctx.Err()))
+			return
+		}
+
+		klog.Infof("Start to run check [%s]", c.Name())
+		res := rp.Run(report.PhaseCheck, c.Name(), func(res *report.Result) error {
+			return c.Run(ctx, env, res)
+		})
+
+		switch res.Status {
+		case report.StatusPass:
+			klog.Infof("Check [%s] passed", c.Name())
+		case report.StatusSkip:
+			klog.Infof("Check [%s] skipped: %s", c.Name(), res.Message)
+		case report.StatusFail:
+			klog.Warningf("Check [%s] failed: %s", c.Name(), res.Message)
+		}
+	}
+}
+
+// Skip records every check as skipped with the given reason.
+func Skip(checks []Check, rp *report.Report, reason string) {
+	for _, c := range checks {
+		rp.Run(report.PhaseCheck, c.Name(), func(res *report.Result) error {
+			res.Skipf("%s", reason)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func names(checks []Check) []string {
+	var n []string
+	for _, c := range checks {
+		n = append(n, c.Name())
+	}
+	return n
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name             string
+		enabled, skipped []string
+		want             []string
+		wantErr          bool
+	}{
+		{name: "all", want: names(All())},
+		{name: "enabled", enabled: []string{"service", "storage"}, want: []string{"service", "storage"}},
+		{name: "comma separated", enabled: []string{"storage,ingress"}, want: []string{"ingress", "storage"}},
+		{name: "skipped", enabled: []string{"service,storage"}, skipped: []string{"storage"}, want: []string{"service"}},
+		{name: "unknown enabled", enabled: []string{"nope"}, wantErr: true},
+		{name: "unknown skipped", skipped: []string{"service,nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(tt.enabled, tt.skipped)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("Select() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAllOrder(t *testing.T) {
+	want := []string{
+		"node-health",
+		"pod-network",
+		"network-policy",
+		"service",
+		"service-headless",
+		"service-nodeport",
+		"service-loadbalancer",
+		"service-externalname",
+		"dns",
+		"ingress",
+		"ingress-tls",
+		"storage",
+		"storage-durability",
+	}
+	if got := names(All()); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of a duplicate check did not panic")
+		}
+	}()
+	Register(&serviceCheck{})
+}
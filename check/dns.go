@@ -0,0 +1,157 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tiggoins/function-checker/report"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// dnsScript resolves every argument with getent and prints one line per name:
+// <name> <exit code> <latency in microseconds> <addresses...>
+const dnsScript = `for name in "$@"; do
+  start=$(date +%s%N)
+  out=$(getent hosts "$name")
+  rc=$?
+  end=$(date +%s%N)
+  echo "$name $rc $(( (end - start) / 1000 )) $(echo $out | awk '{print $1}')"
+done`
+
+type dnsCheck struct{}
+
+// dnsQuery is a name to resolve and the address it must resolve to, empty if any address will do.
+type dnsQuery struct {
+	name string
+	want string
+}
+
+type dnsAnswer struct {
+	ok      bool
+	latency time.Duration
+	address string
+}
+
+func (c *dnsCheck) Name() string {
+	return "dns"
+}
+
+func (c *dnsCheck) Description() string {
+	return "Resolve service, per-pod and external names from inside a statefulset pod"
+}
+
+func (c *dnsCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+
+	queries, err := dnsQueries(ctx, env)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(queries))
+	for _, q := range queries {
+		names = append(names, q.name)
+	}
+	podName := sts.PodNames()[0]
+	stdout, stderr, err := sts.Exec(ctx, client, env.Checker.RestConf, podName,
+		append([]string{"sh", "-c", dnsScript, "sh"}, names...))
+	if err != nil {
+		return fmt.Errorf("resolve names in pod [%s]: %v %s", podName, err, stderr)
+	}
+	answers := parseDNSAnswers(stdout)
+
+	var allErrs []error
+	for _, q := range queries {
+		ans, ok := answers[q.name]
+		switch {
+		case !ok:
+			allErrs = append(allErrs, fmt.Errorf("no answer for [%s]", q.name))
+		case !ans.ok:
+			res.AddEvidence(q.name, fmt.Sprintf("NXDOMAIN or timeout (%s)", ans.latency))
+			allErrs = append(allErrs, fmt.Errorf("cannot resolve [%s]", q.name))
+		case q.want != "" && ans.address != q.want:
+			res.AddEvidence(q.name, fmt.Sprintf("%s (%s)", ans.address, ans.latency))
+			allErrs = append(allErrs, fmt.Errorf("[%s] resolved to %s, want %s", q.name, ans.address, q.want))
+		default:
+			res.AddEvidence(q.name, fmt.Sprintf("%s (%s)", ans.address, ans.latency))
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// dnsQueries lists the service names, the per-pod names of the statefulset and the external name
+// to resolve.
+func dnsQueries(ctx context.Context, env *Env) ([]dnsQuery, error) {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+	ns := sts.Namespace()
+	domain := env.Config.ClusterDomain
+
+	svc, err := client.CoreV1().Services(ns).Get(ctx, env.Service.Name(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	queries := []dnsQuery{
+		{name: svc.Name, want: svc.Spec.ClusterIP},
+		{name: fmt.Sprintf("%s.%s", svc.Name, ns), want: svc.Spec.ClusterIP},
+		{name: fmt.Sprintf("%s.%s.svc.%s", svc.Name, ns, domain), want: svc.Spec.ClusterIP},
+	}
+
+	// Per-pod records only exist when the governing service of the statefulset is headless,
+	// which the checker creates it as.
+	governing, err := client.CoreV1().Services(ns).Get(ctx, sts.ServiceName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if governing.Spec.ClusterIP != corev1.ClusterIPNone {
+		return nil, fmt.Errorf("service [%s] governing the statefulset is not headless, per-pod names cannot be resolved",
+			governing.Name)
+	}
+	for _, podName := range sts.PodNames() {
+		pod, err := client.CoreV1().Pods(ns).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries,
+			dnsQuery{name: fmt.Sprintf("%s.%s", podName, governing.Name), want: pod.Status.PodIP},
+			dnsQuery{name: fmt.Sprintf("%s.%s.%s.svc.%s", podName, governing.Name, ns, domain), want: pod.Status.PodIP})
+	}
+
+	if env.Config.DNSExternalName != "" {
+		queries = append(queries, dnsQuery{name: env.Config.DNSExternalName})
+	}
+
+	return queries, nil
+}
+
+func parseDNSAnswers(output string) map[string]dnsAnswer {
+	answers := map[string]dnsAnswer{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		micros, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		ans := dnsAnswer{
+			ok:      fields[1] == "0",
+			latency: time.Duration(micros) * time.Microsecond,
+		}
+		if len(fields) > 3 {
+			ans.address = fields[3]
+		}
+		answers[fields[0]] = ans
+	}
+
+	return answers
+}
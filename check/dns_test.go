@@ -0,0 +1,94 @@
+package check
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tiggoins/function-checker/config"
+	"github.com/tiggoins/function-checker/resource"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseDNSAnswers(t *testing.T) {
+	output := "k8s-function-checker-svc 0 1532 10.96.12.7\n" +
+		"k8s-function-checker-sts-0.k8s-function-checker-svc 2 5012 \n" +
+		"kubernetes.io 0 21000 147.75.40.148\n" +
+		"garbage line\n"
+
+	want := map[string]dnsAnswer{
+		"k8s-function-checker-svc":                            {ok: true, latency: 1532 * time.Microsecond, address: "10.96.12.7"},
+		"k8s-function-checker-sts-0.k8s-function-checker-svc": {ok: false, latency: 5012 * time.Microsecond},
+		"kubernetes.io":                                       {ok: true, latency: 21 * time.Millisecond, address: "147.75.40.148"},
+	}
+
+	if got := parseDNSAnswers(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDNSAnswers() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDNSQueries(t *testing.T) {
+	const ns = "function-check"
+	sts := resource.NewStatefulSet(ns, "standard", apiresource.MustParse("1Gi"))
+	svc := resource.NewService(ns)
+	headless := resource.NewHeadlessService(ns)
+
+	for _, isHeadless := range []bool{true, false} {
+		governing := headless.Object().(*corev1.Service).DeepCopy()
+		governing.Spec.ClusterIP = "10.96.0.11"
+		if isHeadless {
+			governing.Spec.ClusterIP = corev1.ClusterIPNone
+		}
+		service := svc.Object().(*corev1.Service).DeepCopy()
+		service.Spec.ClusterIP = "10.96.0.10"
+		client := fake.NewSimpleClientset(service, governing)
+		for _, podName := range sts.PodNames() {
+			client.CoreV1().Pods(ns).Create(context.Background(), &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: ns},
+				Status:     corev1.PodStatus{PodIP: "10.244.0." + podName[len(podName)-1:]},
+			}, metav1.CreateOptions{})
+		}
+		env := &Env{
+			Config:      config.CommandArg{ClusterDomain: "cluster.local"},
+			Checker:     &config.Checker{Client: client},
+			StatefulSet: sts,
+			Service:     svc,
+			Headless:    headless,
+		}
+
+		queries, err := dnsQueries(context.Background(), env)
+		if !isHeadless {
+			if err == nil || !strings.Contains(err.Error(), "is not headless") {
+				t.Errorf("dnsQueries() error = %v, want the governing service is not headless", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("dnsQueries() error = %v", err)
+		}
+
+		got := map[string]string{}
+		for _, q := range queries {
+			got[q.name] = q.want
+		}
+		for _, podName := range sts.PodNames() {
+			ip := "10.244.0." + podName[len(podName)-1:]
+			for _, name := range []string{
+				podName + "." + governing.Name,
+				podName + "." + governing.Name + "." + ns + ".svc.cluster.local",
+			} {
+				if want, ok := got[name]; !ok || want != ip {
+					t.Errorf("query of [%s] = %q, %t, want %s", name, want, ok, ip)
+				}
+			}
+		}
+		if want := 3 + 2*len(sts.PodNames()); len(queries) != want {
+			t.Errorf("dnsQueries() = %d queries, want %d", len(queries), want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package check
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tiggoins/function-checker/report"
+	"k8s.io/klog/v2"
+)
+
+type ingressCheck struct{}
+
+func (c *ingressCheck) Name() string {
+	return "ingress"
+}
+
+func (c *ingressCheck) Description() string {
+	return "Access the service through the ingress controller with the ingress host"
+}
+
+func (c *ingressCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	if env.Ingress == nil {
+		res.Skipf("ingress resource was not created")
+		return nil
+	}
+
+	cfg := env.Config
+	if cfg.Interactive {
+		// The host carries the run ID, the user has to open it and not the --host domain.
+		klog.Infof("Waiting for user to access from browser, open http://%s/ (the domain must resolve through the wildcard DNS record *.%s). "+
+			"Press 'y' if the test was successfully, 'n' if it was not.", env.Ingress.Host(), cfg.Domain)
+		if !WaitForUser() {
+			return fmt.Errorf("user reported ingress [%s] is not accessible", env.Ingress.Host())
+		}
+		return nil
+	}
+
+	endpoint, err := env.Ingress.Endpoint(ctx, env.Checker.Client, cfg.IngressNamespace, cfg.IngressEndpoint)
+	if err != nil {
+		return fmt.Errorf("cannot resolve ingress endpoint: %v", err)
+	}
+	res.AddEvidence("endpoint", endpoint)
+
+	body, err := env.Ingress.AccessFromExternal(ctx, endpoint)
+	res.AddEvidence("body", body)
+	return err
+}
+
+type ingressTLSCheck struct{}
+
+func (c *ingressTLSCheck) Name() string {
+	return "ingress-tls"
+}
+
+func (c *ingressTLSCheck) Description() string {
+	return "Access the service through the ingress controller over HTTPS and verify the served certificate"
+}
+
+func (c *ingressTLSCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	if env.Ingress == nil {
+		res.Skipf("ingress resource was not created")
+		return nil
+	}
+	if env.TLSSecret == nil {
+		res.Skipf("ingress is not served over HTTPS, use --ingress-tls to enable it")
+		return nil
+	}
+
+	cfg := env.Config
+	endpoint, err := env.Ingress.TLSEndpoint(ctx, env.Checker.Client, cfg.IngressNamespace, cfg.TLSEndpoint)
+	if err != nil {
+		return fmt.Errorf("cannot resolve ingress endpoint: %v", err)
+	}
+	res.AddEvidence("endpoint", endpoint)
+
+	served, body, err := env.Ingress.AccessFromExternalTLS(ctx, endpoint, env.TLSSecret.CAPool())
+	res.AddEvidence("certificate", served)
+	res.AddEvidence("body", body)
+	return err
+}
+
+func WaitForUser() bool {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		input := scanner.Text()
+		if strings.EqualFold(input, "y") {
+			return true
+		} else if strings.EqualFold(input, "n") {
+			return false
+		} else {
+			klog.Info("Invalid input. Please press 'y' if the test was successful, 'n' if it was not:")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		klog.Error(err)
+		return false
+	}
+
+	return false
+}
@@ -0,0 +1,131 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// policyTimeout is how long a network policy may take to be enforced by the CNI.
+	policyTimeout  = time.Duration(60) * time.Second
+	policyInterval = time.Duration(2) * time.Second
+)
+
+type networkPolicyCheck struct{}
+
+func (c *networkPolicyCheck) Name() string {
+	return "network-policy"
+}
+
+func (c *networkPolicyCheck) Description() string {
+	return "A default-deny ingress policy blocks a client pod from the statefulset and an allow rule by label restores it"
+}
+
+func (c *networkPolicyCheck) Provision(env *Env) []resource.OperatorInterface {
+	env.ClientPod = resource.NewClientPod(env.Config.Namespace)
+	return []resource.OperatorInterface{env.ClientPod}
+}
+
+func (c *networkPolicyCheck) LateProvision(env *Env) []resource.OperatorInterface {
+	env.DenyPolicy = resource.NewDenyIngressPolicy(env.Config.Namespace)
+	env.AllowPolicy = resource.NewAllowClientPolicy(env.Config.Namespace)
+	return []resource.OperatorInterface{env.DenyPolicy, env.AllowPolicy}
+}
+
+func (c *networkPolicyCheck) Run(ctx context.Context, env *Env, res *report.Result) (err error) {
+	client := env.Checker.Client
+	ns := env.Config.Namespace
+
+	if err := env.ClientPod.WaitForReady(ctx, client, env.Config.ReadyTimeout); err != nil {
+		return err
+	}
+	backend, err := client.CoreV1().Pods(ns).Get(ctx, env.StatefulSet.PodNames()[0], metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	target := net.JoinHostPort(backend.Status.PodIP, "80")
+	res.AddEvidence("target", fmt.Sprintf("%s(%s)", backend.Name, target))
+
+	if err := c.waitFor(ctx, env, target, true); err != nil {
+		res.AddEvidence("without policy", "blocked")
+		return fmt.Errorf("client cannot reach [%s] before any policy is applied: %v", target, err)
+	}
+	res.AddEvidence("without policy", "reachable")
+
+	deny, allow := env.DenyPolicy, env.AllowPolicy
+	// The policies would break the checks running after this one, remove them as soon as it ends
+	// and wait until the CNI stops enforcing them. Cleanup still deletes them if this fails.
+	env.Resources.Add(deny, allow)
+	defer func() {
+		// The run context may already be cancelled, the policies are still removed but
+		// bounded so a hanging API server does not block the remaining checks.
+		delCtx, cancel := context.WithTimeout(context.Background(), policyTimeout)
+		defer cancel()
+		var deleted bool
+		for _, p := range []resource.OperatorInterface{allow, deny} {
+			if !p.IsCreated() {
+				continue
+			}
+			if delErr := p.Delete(delCtx, client); delErr != nil {
+				klog.Warningf("Error happened when delete resource [%s]: %v", p.FormatedName(), delErr)
+				continue
+			}
+			deleted = true
+		}
+		if !deleted {
+			return
+		}
+		if waitErr := c.waitFor(ctx, env, target, true); waitErr != nil {
+			res.AddEvidence("policies deleted", "blocked")
+			if err == nil {
+				err = fmt.Errorf("client cannot reach [%s] after the policies are deleted: %v", target, waitErr)
+			}
+			return
+		}
+		res.AddEvidence("policies deleted", "reachable")
+	}()
+
+	if err := deny.Create(ctx, client); err != nil {
+		return err
+	}
+	if err := c.waitFor(ctx, env, target, false); err != nil {
+		res.AddEvidence("deny ingress", "reachable")
+		return fmt.Errorf("[%s] is not enforced, the CNI may not support network policies: %v", deny.FormatedName(), err)
+	}
+	res.AddEvidence("deny ingress", "blocked")
+
+	if err := allow.Create(ctx, client); err != nil {
+		return err
+	}
+	if err := c.waitFor(ctx, env, target, true); err != nil {
+		res.AddEvidence("allow client", "blocked")
+		return fmt.Errorf("[%s] is not enforced: %v", allow.FormatedName(), err)
+	}
+	res.AddEvidence("allow client", "reachable")
+
+	return nil
+}
+
+// waitFor requests target from the client pod until its reachability is as expected.
+func (c *networkPolicyCheck) waitFor(ctx context.Context, env *Env, target string, reachable bool) error {
+	waitCtx, cancel := context.WithTimeout(ctx, policyTimeout)
+	defer cancel()
+
+	return wait.PollImmediateUntil(policyInterval, func() (bool, error) {
+		stdout, stderr, err := env.ClientPod.Exec(waitCtx, env.Checker.Client, env.Checker.RestConf,
+			[]string{"sh", "-c", podNetworkScript, "sh", target})
+		if err != nil {
+			klog.Infof("Request [%s] from client pod: %v %s", target, err, stderr)
+			return false, nil
+		}
+		return (parseProbeResults(stdout)[target] == "ok") == reachable, nil
+	}, waitCtx.Done())
+}
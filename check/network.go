@@ -0,0 +1,113 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tiggoins/function-checker/report"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podNetworkScript requests every host:port argument and prints "<host:port> ok|fail" per target.
+const podNetworkScript = `for target in "$@"; do
+  if curl -fsS -m 5 -o /dev/null "http://$target/"; then echo "$target ok"; else echo "$target fail"; fi
+done`
+
+type podNetworkCheck struct{}
+
+func (c *podNetworkCheck) Name() string {
+	return "pod-network"
+}
+
+func (c *podNetworkCheck) Description() string {
+	return "Every statefulset replica reaches every other replica by pod IP"
+}
+
+func (c *podNetworkCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+
+	pods, err := statefulSetPods(ctx, env)
+	if err != nil {
+		return err
+	}
+
+	// matrix[i][j] is the result of pods[i] requesting pods[j].
+	matrix := make([][]string, len(pods))
+	var failed []string
+	for i, from := range pods {
+		matrix[i] = make([]string, len(pods))
+
+		var targets []string
+		for j, to := range pods {
+			if i == j {
+				matrix[i][j] = "-"
+				continue
+			}
+			targets = append(targets, net.JoinHostPort(to.Status.PodIP, "80"))
+		}
+
+		stdout, stderr, err := sts.Exec(ctx, client, env.Checker.RestConf, from.Name,
+			append([]string{"sh", "-c", podNetworkScript, "sh"}, targets...))
+		if err != nil {
+			return fmt.Errorf("request pods from [%s]: %v %s", from.Name, err, stderr)
+		}
+		results := parseProbeResults(stdout)
+
+		for j, to := range pods {
+			if i == j {
+				continue
+			}
+			if results[net.JoinHostPort(to.Status.PodIP, "80")] == "ok" {
+				matrix[i][j] = "ok"
+				continue
+			}
+			matrix[i][j] = "FAIL"
+			failed = append(failed, fmt.Sprintf("%s -> %s", podLabel(from), podLabel(to)))
+		}
+	}
+
+	res.AddEvidence("matrix", formatMatrix(pods, matrix))
+	if len(failed) > 0 {
+		return fmt.Errorf("pod to pod requests failed: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// parseProbeResults parses the output of podNetworkScript into the result of each target.
+func parseProbeResults(output string) map[string]string {
+	results := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			results[fields[0]] = fields[1]
+		}
+	}
+
+	return results
+}
+
+func podLabel(pod *corev1.Pod) string {
+	return fmt.Sprintf("%s(%s)", pod.Name, pod.Spec.NodeName)
+}
+
+func formatMatrix(pods []*corev1.Pod, matrix [][]string) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	header := []string{"FROM\\TO"}
+	for _, pod := range pods {
+		header = append(header, podLabel(pod))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i, pod := range pods {
+		fmt.Fprintln(w, podLabel(pod)+"\t"+strings.Join(matrix[i], "\t"))
+	}
+	w.Flush()
+
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package check
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFormatMatrix(t *testing.T) {
+	pod := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.PodSpec{NodeName: node}}
+	}
+	pods := []*corev1.Pod{pod("sts-0", "node-a"), pod("sts-1", "node-b")}
+	matrix := [][]string{{"-", "ok"}, {"FAIL", "-"}}
+
+	want := "FROM\\TO        sts-0(node-a)  sts-1(node-b)\n" +
+		"sts-0(node-a)  -              ok\n" +
+		"sts-1(node-b)  FAIL           -\n"
+	if got := formatMatrix(pods, matrix); got != want {
+		t.Errorf("formatMatrix() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// nodeProbeScript prints the HTTP status of the apiserver healthz endpoint reached through the
+// kubernetes service environment, then the address the kubernetes service name resolves to.
+const nodeProbeScript = `curl -sk -m 5 -o /dev/null -w '%{http_code}\n' "https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT/healthz"
+getent hosts "$1" | awk '{print $1; exit}'`
+
+type nodeHealthCheck struct{}
+
+func (c *nodeHealthCheck) Name() string {
+	return "node-health"
+}
+
+func (c *nodeHealthCheck) Description() string {
+	return "A daemonset pod on every schedulable node pulls the image, starts, reaches the apiserver and resolves DNS"
+}
+
+func (c *nodeHealthCheck) Provision(env *Env) []resource.OperatorInterface {
+	env.DaemonSet = resource.NewDaemonSet(env.Config.Namespace, env.Config.TolerateMaster)
+	return []resource.OperatorInterface{env.DaemonSet}
+}
+
+func (c *nodeHealthCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	ds := env.DaemonSet
+
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	var nodes []string
+	for i := range nodeList.Items {
+		if ds.Schedulable(&nodeList.Items[i]) {
+			nodes = append(nodes, nodeList.Items[i].Name)
+		} else {
+			res.AddEvidence(nodeList.Items[i].Name, "not schedulable, skipped")
+		}
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no schedulable node found")
+	}
+
+	var allErrs []error
+	pods, err := ds.WaitForPods(ctx, client, nodes, env.Config.ReadyTimeout)
+	if err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	kubernetesName := "kubernetes.default.svc." + env.Config.ClusterDomain
+	for _, node := range nodes {
+		pod, ok := pods[node]
+		if !ok || !resource.IsPodReady(pod) {
+			res.AddEvidence(node, "probe pod not ready")
+			continue
+		}
+
+		stdout, stderr, err := ds.Exec(ctx, client, env.Checker.RestConf, pod.Name,
+			[]string{"sh", "-c", nodeProbeScript, "sh", kubernetesName})
+		if err != nil {
+			res.AddEvidence(node, fmt.Sprintf("exec failed: %v %s", err, stderr))
+			allErrs = append(allErrs, fmt.Errorf("node [%s]: exec in pod [%s]: %v", node, pod.Name, err))
+			continue
+		}
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		apiStatus, address := "000", ""
+		if len(lines) > 0 {
+			apiStatus = strings.TrimSpace(lines[0])
+		}
+		if len(lines) > 1 {
+			address = strings.TrimSpace(lines[1])
+		}
+		res.AddEvidence(node, fmt.Sprintf("pod=%s image pulled, container started, apiserver status=%s, %s=%s",
+			pod.Name, apiStatus, kubernetesName, address))
+
+		// Any HTTP status proves the apiserver was reached, authorization is not of interest here.
+		if apiStatus == "000" || apiStatus == "" {
+			allErrs = append(allErrs, fmt.Errorf("node [%s]: cannot reach apiserver", node))
+		}
+		if address == "" {
+			allErrs = append(allErrs, fmt.Errorf("node [%s]: cannot resolve [%s]", node, kubernetesName))
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
@@ -0,0 +1,273 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// headlessScript prints every address the name in the first argument resolves to, one per line.
+const headlessScript = `getent ahostsv4 "$1" | awk '{print $1}' | sort -u`
+
+type serviceCheck struct{}
+
+func (c *serviceCheck) Name() string {
+	return "service"
+}
+
+func (c *serviceCheck) Description() string {
+	return "Access the ClusterIP service from inside a statefulset pod"
+}
+
+func (c *serviceCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	host := fmt.Sprintf("%s.%s", env.Service.Name(), env.StatefulSet.Namespace())
+	output, err := env.StatefulSet.AccessFromInternal(ctx, env.Checker.Client, env.Checker.RestConf, host)
+	res.AddEvidence("output", output)
+	return err
+}
+
+type headlessServiceCheck struct{}
+
+func (c *headlessServiceCheck) Name() string {
+	return "service-headless"
+}
+
+func (c *headlessServiceCheck) Description() string {
+	return "The headless service lists every statefulset replica as endpoint and resolves to their pod IPs"
+}
+
+func (c *headlessServiceCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+
+	pods, err := statefulSetPods(ctx, env)
+	if err != nil {
+		return err
+	}
+	var want []string
+	for _, pod := range pods {
+		want = append(want, pod.Status.PodIP)
+	}
+	sort.Strings(want)
+	res.AddEvidence("pod ips", strings.Join(want, " "))
+
+	var allErrs []error
+	if err := env.Headless.VerifyEndpoints(ctx, client, pods); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	name := fmt.Sprintf("%s.%s", env.Headless.Name(), sts.Namespace())
+	podName := sts.PodNames()[0]
+	stdout, stderr, err := sts.Exec(ctx, client, env.Checker.RestConf, podName,
+		[]string{"sh", "-c", headlessScript, "sh", name})
+	if err != nil {
+		return utilerrors.NewAggregate(append(allErrs, fmt.Errorf("resolve [%s] in pod [%s]: %v %s",
+			name, podName, err, stderr)))
+	}
+	got := strings.Fields(stdout)
+	sort.Strings(got)
+	res.AddEvidence(name, strings.Join(got, " "))
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		allErrs = append(allErrs, fmt.Errorf("[%s] resolved to %v, want %v", name, got, want))
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+type nodePortServiceCheck struct{}
+
+func (c *nodePortServiceCheck) Name() string {
+	return "service-nodeport"
+}
+
+func (c *nodePortServiceCheck) Description() string {
+	return "Access the NodePort service on every ready node from inside a statefulset pod"
+}
+
+func (c *nodePortServiceCheck) Provision(env *Env) []resource.OperatorInterface {
+	env.NodePort = resource.NewNodePortService(env.Config.Namespace)
+	return []resource.OperatorInterface{env.NodePort}
+}
+
+func (c *nodePortServiceCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+
+	svc, err := env.NodePort.Get(ctx, client)
+	if err != nil {
+		return err
+	}
+	if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+		return fmt.Errorf("no node port allocated to [%s]", env.NodePort.FormatedName())
+	}
+	port := strconv.Itoa(int(svc.Spec.Ports[0].NodePort))
+	res.AddEvidence("node port", port)
+
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	targets := map[string]string{}
+	var names []string
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if !resource.IsNodeReady(node) {
+			res.AddEvidence(node.Name, "not ready, skipped")
+			continue
+		}
+		address := nodeInternalIP(node)
+		if address == "" {
+			res.AddEvidence(node.Name, "no internal ip, skipped")
+			continue
+		}
+		targets[node.Name] = net.JoinHostPort(address, port)
+		names = append(names, node.Name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no ready node with internal ip found")
+	}
+
+	args := []string{"sh", "-c", podNetworkScript, "sh"}
+	for _, name := range names {
+		args = append(args, targets[name])
+	}
+	podName := sts.PodNames()[0]
+	stdout, stderr, err := sts.Exec(ctx, client, env.Checker.RestConf, podName, args)
+	if err != nil {
+		return fmt.Errorf("request node ports from [%s]: %v %s", podName, err, stderr)
+	}
+	results := parseProbeResults(stdout)
+
+	var failed []string
+	for _, name := range names {
+		result := results[targets[name]]
+		if result == "" {
+			result = "fail"
+		}
+		res.AddEvidence(name, fmt.Sprintf("%s %s", targets[name], result))
+		if result != "ok" {
+			failed = append(failed, fmt.Sprintf("%s(%s)", name, targets[name]))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("node port requests failed: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+type loadBalancerServiceCheck struct{}
+
+func (c *loadBalancerServiceCheck) Name() string {
+	return "service-loadbalancer"
+}
+
+func (c *loadBalancerServiceCheck) Description() string {
+	return "Access the LoadBalancer service through its address, skipped if no address is assigned"
+}
+
+func (c *loadBalancerServiceCheck) Provision(env *Env) []resource.OperatorInterface {
+	env.LoadBalancer = resource.NewLoadBalancerService(env.Config.Namespace)
+	return []resource.OperatorInterface{env.LoadBalancer}
+}
+
+func (c *loadBalancerServiceCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	address, err := env.LoadBalancer.WaitForLoadBalancer(ctx, env.Checker.Client, env.Config.LBTimeout)
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		res.Skipf("no load balancer address assigned to [%s] within %s, the cluster may have no load balancer provider",
+			env.LoadBalancer.FormatedName(), env.Config.LBTimeout)
+		return nil
+	}
+	res.AddEvidence("address", address)
+
+	body, err := env.LoadBalancer.AccessFromExternal(ctx, address)
+	res.AddEvidence("body", body)
+	return err
+}
+
+type externalNameServiceCheck struct{}
+
+func (c *externalNameServiceCheck) Name() string {
+	return "service-externalname"
+}
+
+func (c *externalNameServiceCheck) Description() string {
+	return "Resolve and access an ExternalName service aliasing the ClusterIP service from inside a statefulset pod"
+}
+
+func (c *externalNameServiceCheck) Provision(env *Env) []resource.OperatorInterface {
+	ns := env.Config.Namespace
+	env.ExternalName = resource.NewExternalNameService(ns,
+		fmt.Sprintf("%s.%s.svc.%s", env.Service.Name(), ns, env.Config.ClusterDomain))
+	return []resource.OperatorInterface{env.ExternalName}
+}
+
+func (c *externalNameServiceCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	client := env.Checker.Client
+	sts := env.StatefulSet
+	res.AddEvidence("external name", env.ExternalName.ExternalName())
+
+	target, err := env.Service.Get(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s.%s", env.ExternalName.Name(), sts.Namespace())
+	podName := sts.PodNames()[0]
+	stdout, stderr, err := sts.Exec(ctx, client, env.Checker.RestConf, podName,
+		[]string{"sh", "-c", dnsScript, "sh", name})
+	if err != nil {
+		return fmt.Errorf("resolve [%s] in pod [%s]: %v %s", name, podName, err, stderr)
+	}
+	ans, ok := parseDNSAnswers(stdout)[name]
+	if !ok || !ans.ok {
+		return fmt.Errorf("cannot resolve [%s]", name)
+	}
+	res.AddEvidence(name, fmt.Sprintf("%s (%s)", ans.address, ans.latency))
+	if ans.address != target.Spec.ClusterIP {
+		return fmt.Errorf("[%s] resolved to %s, want %s of [%s]", name, ans.address, target.Spec.ClusterIP,
+			env.Service.FormatedName())
+	}
+
+	output, err := sts.AccessFromInternal(ctx, client, env.Checker.RestConf, name)
+	res.AddEvidence("output", output)
+	return err
+}
+
+// statefulSetPods returns the pods of the statefulset in the order of their ordinal.
+func statefulSetPods(ctx context.Context, env *Env) ([]*corev1.Pod, error) {
+	sts := env.StatefulSet
+
+	var pods []*corev1.Pod
+	for _, podName := range sts.PodNames() {
+		pod, err := env.Checker.Client.CoreV1().Pods(sts.Namespace()).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+func nodeInternalIP(node *corev1.Node) string {
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			return addr.Address
+		}
+	}
+
+	return ""
+}
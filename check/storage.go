@@ -0,0 +1,120 @@
+package check
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+)
+
+const durabilityFile = "function-checker.dat"
+
+type storageCheck struct{}
+
+func (c *storageCheck) Name() string {
+	return "storage"
+}
+
+func (c *storageCheck) Description() string {
+	return "Every statefulset replica gets a bound persistentvolumeclaim from the storageclass"
+}
+
+func (c *storageCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	sts := env.StatefulSet
+
+	var allErrs []error
+	for _, podName := range sts.PodNames() {
+		claimName := sts.ClaimName(podName)
+		pvc, err := env.Checker.Client.CoreV1().PersistentVolumeClaims(sts.Namespace()).
+			Get(ctx, claimName, metav1.GetOptions{})
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+
+		if pvc.Status.Phase != corev1.ClaimBound {
+			allErrs = append(allErrs, fmt.Errorf("persistentvolumeclaim [%s] is %s", claimName, pvc.Status.Phase))
+			continue
+		}
+
+		capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		res.AddEvidence(claimName, fmt.Sprintf("volume=%s storageclass=%s capacity=%s",
+			pvc.Spec.VolumeName, stringValue(pvc.Spec.StorageClassName), capacity.String()))
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type durabilityCheck struct{}
+
+func (c *durabilityCheck) Name() string {
+	return "storage-durability"
+}
+
+func (c *durabilityCheck) Description() string {
+	return "Data written to the persistentvolume survives a restart of every replica"
+}
+
+func (c *durabilityCheck) Run(ctx context.Context, env *Env, res *report.Result) error {
+	sts := env.StatefulSet
+	path := resource.DataMountPath + "/" + durabilityFile
+
+	for _, podName := range sts.PodNames() {
+		data := podName + "-" + rand.String(32)
+		sum := sha256.Sum256([]byte(data))
+		checksum := hex.EncodeToString(sum[:])
+
+		stdout, stderr, err := sts.Exec(ctx, env.Checker.Client, env.Checker.RestConf, podName,
+			[]string{"sh", "-c", `printf '%s' "$1" > "$2" && sync && sha256sum "$2"`, "sh", data, path})
+		if err != nil {
+			return fmt.Errorf("write %s in pod [%s]: %v %s", path, podName, err, stderr)
+		}
+		if written := strings.Fields(stdout); len(written) == 0 || written[0] != checksum {
+			return fmt.Errorf("checksum of %s written in pod [%s] is %q, want %s", path, podName, stdout, checksum)
+		}
+
+		before, err := env.Checker.Client.CoreV1().Pods(sts.Namespace()).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		after, err := sts.RecreatePod(ctx, env.Checker.Client, podName, env.Config.ReadyTimeout)
+		if err != nil {
+			return err
+		}
+
+		stdout, stderr, err = sts.Exec(ctx, env.Checker.Client, env.Checker.RestConf, podName,
+			[]string{"sha256sum", path})
+		if err != nil {
+			return fmt.Errorf("read %s in recreated pod [%s]: %v %s", path, podName, err, stderr)
+		}
+		if read := strings.Fields(stdout); len(read) == 0 || read[0] != checksum {
+			return fmt.Errorf("checksum of %s in recreated pod [%s] is %q, want %s", path, podName, stdout, checksum)
+		}
+
+		res.AddEvidence(podName, fmt.Sprintf("sha256=%s claim=%s node %s -> %s", checksum,
+			sts.ClaimName(podName), before.Spec.NodeName, after.Spec.NodeName))
+
+		if _, stderr, err := sts.Exec(ctx, env.Checker.Client, env.Checker.RestConf, podName,
+			[]string{"rm", "-f", path}); err != nil {
+			klog.Warningf("Cannot remove %s in pod [%s]: %v %s", path, podName, err, stderr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tiggoins/function-checker/resource"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/metadata"
+	"k8s.io/klog/v2"
+)
+
+// Object is an object left behind by a run.
+type Object struct {
+	// Resource is the plural resource name, e.g. statefulsets.
+	Resource  string
+	Namespace string
+	Name      string
+	RunID     string
+	Created   time.Time
+}
+
+func (o Object) FormatedName() string {
+	if o.Namespace == "" {
+		return strings.Join([]string{o.Resource, o.Name}, "/")
+	}
+	return strings.Join([]string{o.Namespace, o.Resource, o.Name}, "/")
+}
+
+// kind is a resource the checker creates, kinds are ordered so that objects are deleted
+// before those they depend on.
+type kind struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+var kinds = []kind{
+	{gvr: networkingv1.SchemeGroupVersion.WithResource("networkpolicies"), namespaced: true},
+	{gvr: networkingv1.SchemeGroupVersion.WithResource("ingresses"), namespaced: true},
+	{gvr: corev1.SchemeGroupVersion.WithResource("services"), namespaced: true},
+	{gvr: appsv1.SchemeGroupVersion.WithResource("statefulsets"), namespaced: true},
+	{gvr: appsv1.SchemeGroupVersion.WithResource("daemonsets"), namespaced: true},
+	{gvr: corev1.SchemeGroupVersion.WithResource("pods"), namespaced: true},
+	{gvr: corev1.SchemeGroupVersion.WithResource("configmaps"), namespaced: true},
+	{gvr: corev1.SchemeGroupVersion.WithResource("secrets"), namespaced: true},
+	// Claims outlive their statefulset, they are deleted once it is gone.
+	{gvr: corev1.SchemeGroupVersion.WithResource("persistentvolumeclaims"), namespaced: true},
+	// Namespaces are created by runs with --ephemeral-namespace.
+	{gvr: corev1.SchemeGroupVersion.WithResource("namespaces")},
+}
+
+// Cleaner finds and deletes the objects left behind by runs which could not clean up,
+// e.g. because the process was killed.
+type Cleaner struct {
+	// Client only reads and deletes metadata, which every kind shares.
+	Client metadata.Interface
+	// Selector selects the objects created by the checker.
+	Selector string
+	// OlderThan ignores objects created less than it ago, so that runs in progress are
+	// left alone. Zero finds every object.
+	OlderThan time.Duration
+}
+
+// Find lists the objects selected in every namespace, in the order they are deleted.
+// Objects owned by a controller are left to the garbage collector and objects in a
+// namespace which is deleted as well are left to the namespace controller.
+func (c *Cleaner) Find(ctx context.Context) ([]Object, error) {
+	opts := metav1.ListOptions{LabelSelector: c.Selector}
+	now := time.Now()
+
+	found := map[string][]metav1.Object{}
+	var allErrs []error
+	for _, k := range kinds {
+		list, err := c.Client.Resource(k.gvr).Namespace(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("list %s: %v", k.gvr.Resource, err))
+			continue
+		}
+		for i := range list.Items {
+			found[k.gvr.Resource] = append(found[k.gvr.Resource], &list.Items[i])
+		}
+	}
+
+	deletedNamespaces := map[string]bool{}
+	for _, ns := range found["namespaces"] {
+		if c.oldEnough(ns, now) {
+			deletedNamespaces[ns.GetName()] = true
+		}
+	}
+
+	var orphans []Object
+	for _, k := range kinds {
+		for _, obj := range found[k.gvr.Resource] {
+			if !c.oldEnough(obj, now) || metav1.GetControllerOf(obj) != nil {
+				continue
+			}
+			if k.namespaced && deletedNamespaces[obj.GetNamespace()] {
+				continue
+			}
+			orphans = append(orphans, Object{
+				Resource:  k.gvr.Resource,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				RunID:     obj.GetLabels()[resource.RunIDLabel],
+				Created:   obj.GetCreationTimestamp().Time,
+			})
+		}
+	}
+
+	return orphans, utilerrors.NewAggregate(allErrs)
+}
+
+func (c *Cleaner) oldEnough(obj metav1.Object, now time.Time) bool {
+	return now.Sub(obj.GetCreationTimestamp().Time) >= c.OlderThan
+}
+
+// Delete deletes objects in order, objects which are already gone are ignored.
+func (c *Cleaner) Delete(ctx context.Context, objects []Object) error {
+	var allErrs []error
+	for _, obj := range objects {
+		k, ok := kindOf(obj.Resource)
+		if !ok {
+			allErrs = append(allErrs, fmt.Errorf("unknown resource [%s]", obj.Resource))
+			continue
+		}
+		err := c.Client.Resource(k.gvr).Namespace(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			klog.Infoln(err.Error())
+			allErrs = append(allErrs, fmt.Errorf("error deleting resource: %v ", err))
+			continue
+		}
+		klog.Infof("Resource [%s] delete successfully", obj.FormatedName())
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func kindOf(name string) (kind, bool) {
+	for _, k := range kinds {
+		if k.gvr.Resource == name {
+			return k, true
+		}
+	}
+
+	return kind{}, false
+}
+
+// Print writes objects as a table with their age at now.
+func Print(w io.Writer, objects []Object, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tNAMESPACE\tNAME\tRUN ID\tAGE")
+	for _, obj := range objects {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", obj.Resource, obj.Namespace, obj.Name, obj.RunID,
+			duration.HumanDuration(now.Sub(obj.Created)))
+	}
+
+	return tw.Flush()
+}
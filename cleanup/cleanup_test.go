@@ -0,0 +1,107 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/metadata/fake"
+)
+
+const testSelector = "component=k8s-function-checker"
+
+// object builds the metadata of an object of kind in apiVersion, as the metadata client sees it.
+func object(apiVersion, kind, namespace, name string, age time.Duration, labeled bool) *metav1.PartialObjectMetadata {
+	m := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+	}
+	if labeled {
+		m.Labels = map[string]string{"component": "k8s-function-checker", "run-id": "abc123"}
+	}
+	return m
+}
+
+func TestFindDelete(t *testing.T) {
+	sts := object("apps/v1", "StatefulSet", "default", "k8s-function-checker-sts-abc123", 2*time.Hour, true)
+	owned := object("v1", "Pod", "default", "k8s-function-checker-sts-abc123-0", 2*time.Hour, true)
+	isController := true
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: sts.Name, Controller: &isController}}
+
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	client := fake.NewSimpleMetadataClient(scheme,
+		sts,
+		owned,
+		object("v1", "PersistentVolumeClaim", "default", "pvc-k8s-function-checker-sts-abc123-0", 2*time.Hour, true),
+		object("v1", "ConfigMap", "default", "k8s-function-checker-cm-abc123", 2*time.Hour, true),
+		// A run in progress.
+		object("v1", "Service", "default", "k8s-function-checker-svc-def456", time.Minute, true),
+		// Not created by the checker.
+		object("v1", "ConfigMap", "default", "unrelated", 2*time.Hour, false),
+		// An ephemeral namespace and an object in it, deleted along with the namespace.
+		object("v1", "Namespace", "", "k8s-function-checker-abc123", 2*time.Hour, true),
+		object("v1", "ConfigMap", "k8s-function-checker-abc123", "k8s-function-checker-cm-abc123", 2*time.Hour, true),
+	)
+
+	cleaner := &Cleaner{Client: client, Selector: testSelector, OlderThan: time.Hour}
+	objects, err := cleaner.Find(context.Background())
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	var names []string
+	for _, obj := range objects {
+		names = append(names, obj.FormatedName())
+	}
+	want := "default/statefulsets/k8s-function-checker-sts-abc123," +
+		"default/configmaps/k8s-function-checker-cm-abc123," +
+		"default/persistentvolumeclaims/pvc-k8s-function-checker-sts-abc123-0," +
+		"namespaces/k8s-function-checker-abc123"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("Find() = %s, want %s", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, objects, time.Now()); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "abc123") || !strings.Contains(out.String(), "120m") {
+		t.Errorf("Print() = %s, want run id and age", out.String())
+	}
+
+	if err := cleaner.Delete(context.Background(), objects); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// Deleting again ignores objects which are already gone.
+	if err := cleaner.Delete(context.Background(), objects); err != nil {
+		t.Errorf("Delete() of deleted objects error = %v", err)
+	}
+
+	ctx := context.Background()
+	get := func(k kind, namespace, name string) error {
+		_, err := client.Resource(k.gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	}
+	statefulsets, _ := kindOf("statefulsets")
+	services, _ := kindOf("services")
+	configmaps, _ := kindOf("configmaps")
+	if err := get(statefulsets, "default", sts.Name); !apierrors.IsNotFound(err) {
+		t.Errorf("statefulset left behind was not deleted: %v", err)
+	}
+	if err := get(services, "default", "k8s-function-checker-svc-def456"); err != nil {
+		t.Errorf("service of the run in progress was deleted: %v", err)
+	}
+	if err := get(configmaps, "default", "unrelated"); err != nil {
+		t.Errorf("configmap not created by the checker was deleted: %v", err)
+	}
+}
@@ -2,8 +2,10 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -11,7 +13,6 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	storageutil "k8s.io/kubectl/pkg/util/storage"
 )
@@ -19,31 +20,75 @@ import (
 const (
 	IngressClassArg      = "--ingress-class="
 	IngressContainerName = "controller"
+
+	// DryRunClient prints the resources of a run, DryRunServer submits them with server-side dry run.
+	DryRunClient = "client"
+	DryRunServer = "server"
+
+	// KeepNever, KeepOnFailure and KeepAlways tell when the resources of a run are left in place.
+	KeepNever     = "never"
+	KeepOnFailure = "on-failure"
+	KeepAlways    = "always"
 )
 
 type CommandArg struct {
-	Namespace        string
-	IngressNamespace string
-	Storageclass     string
-	Capacity         string
-	Domain           string
+	ConfigFile string
+	Profile    string
+	Kubeconfig string
+	Context    string
+	Namespace  string
+	// EphemeralNamespace creates a namespace for the run instead of using Namespace,
+	// it is deleted along with everything in it at the end of the run. The ClusterRole
+	// NamespaceRole is bound inside it to the ServiceAccount NamespaceRoleSA(namespace:name).
+	EphemeralNamespace bool
+	PodSecurityLevel   string
+	NamespaceRole      string
+	NamespaceRoleSA    string
+	NamespaceTimeout   time.Duration
+	IngressNamespace   string
+	Storageclass       string
+	Capacity           string
+	Domain             string
+	ReadyTimeout       time.Duration
+	LBTimeout          time.Duration
+	ClusterDomain      string
+	DNSExternalName    string
+	TolerateMaster     bool
+	IngressEndpoint    string
+	IngressTLS         bool
+	TLSEndpoint        string
+	Interactive        bool
+	DryRun             string
+	Keep               string
+	Output             string
+	JUnitFile          string
+	DiagnosticsDir     string
+	DiagnosticsTar     bool
+	Checks             []string
+	SkipChecks         []string
 }
 
 type Checker struct {
 	flag     CommandArg
-	Client   *kubernetes.Clientset
+	Client   kubernetes.Interface
 	RestConf *rest.Config
-	Ctx      context.Context
-	Cancel   context.CancelFunc
+	// Source describes where RestConf was loaded from.
+	Source string
+	Ctx    context.Context
+	Cancel context.CancelFunc
 }
 
-func NewChecker(cg CommandArg) *Checker {
-	rc, err := clientcmd.BuildConfigFromFlags("", clientcmd.RecommendedHomeFile)
+func NewChecker(cg CommandArg) (*Checker, error) {
+	rc, source, err := RestConfig(cg.Kubeconfig, cg.Context)
 	if err != nil {
-		klog.Exitln(err.Error())
+		return nil, err
 	}
+	klog.Infof("Connect to cluster [%s] with %s", rc.Host, source)
 
-	client := kubernetes.NewForConfigOrDie(rc)
+	client, err := kubernetes.NewForConfig(rc)
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Checker{
@@ -52,7 +97,8 @@ func NewChecker(cg CommandArg) *Checker {
 		Cancel:   cancel,
 		Client:   client,
 		RestConf: rc,
-	}
+		Source:   source,
+	}, nil
 }
 
 func (c *Checker) GetDefaultIngressClass() string {
@@ -90,6 +136,9 @@ func (c *Checker) GetIngressAnnotationValue() string {
 		klog.Warningln(err.Error())
 		return ""
 	}
+	if len(pods.Items) == 0 {
+		return ""
+	}
 
 	for _, container := range pods.Items[0].Spec.Containers {
 		if strings.EqualFold(container.Name, IngressContainerName) {
@@ -107,50 +156,60 @@ func (c *Checker) GetIngressAnnotationValue() string {
 	return ""
 }
 
-func (c *Checker) GetDefaultStorageClass() string {
+func (c *Checker) GetDefaultStorageClass() (string, error) {
 	storageClasses, err := c.Client.StorageV1().StorageClasses().List(c.Ctx, metav1.ListOptions{})
 	if err != nil {
-		klog.Warningln(err.Error())
-		return ""
+		return "", err
 	}
 
 	for _, storageclass := range storageClasses.Items {
 		if _, ok := storageclass.Annotations[storageutil.IsDefaultStorageClassAnnotation]; ok {
 			klog.Infof("Get default storageclass=[%s]", storageclass.Name)
-			return storageclass.Name
+			return storageclass.Name, nil
 		}
 	}
 
-	klog.Exitf("Cannot find default storageclass,please use -s <storageclass> specify which storageclass to use")
-	return ""
+	return "", fmt.Errorf("cannot find default storageclass,please use -s <storageclass> specify which storageclass to use")
 }
 
-func (c *Checker) VerifyFlags() {
+func (c *Checker) VerifyFlags() error {
 	if c.flag.Namespace == "" {
 		klog.Infoln("Namespace is empty,will use `default` namespace")
 	}
 
 	_, err := c.Client.StorageV1().StorageClasses().Get(c.Ctx, c.flag.Storageclass, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		klog.Exitf("storagclass [%s] not found", c.flag.Storageclass)
+		return fmt.Errorf("storagclass [%s] not found", c.flag.Storageclass)
 	}
 
-	_, err = c.Client.CoreV1().Namespaces().Get(c.Ctx, c.flag.Namespace, metav1.GetOptions{})
-	if apierrors.IsNotFound(err) {
-		klog.Exitf("namespace [%s] not found", c.flag.Namespace)
+	if !c.flag.EphemeralNamespace {
+		_, err = c.Client.CoreV1().Namespaces().Get(c.Ctx, c.flag.Namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("namespace [%s] not found", c.flag.Namespace)
+		}
 	}
 
+	if c.flag.IngressNamespace == "" {
+		return fmt.Errorf("--ingress-namespace is required")
+	}
 	_, err = c.Client.CoreV1().Namespaces().Get(c.Ctx, c.flag.IngressNamespace, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		klog.Exitf("namespace [%s] not found", c.flag.IngressNamespace)
+		return fmt.Errorf("namespace [%s] not found", c.flag.IngressNamespace)
 	}
 
-	if !strings.HasSuffix(c.flag.Capacity, "Gi") {
-		klog.Exit("capacity must be gigabytes,eg., 50Gi")
+	return VerifyCapacity(c.flag.Capacity)
+}
+
+// VerifyCapacity checks that capacity is a whole number of gigabytes, it needs no cluster.
+func VerifyCapacity(capacity string) error {
+	if !strings.HasSuffix(capacity, "Gi") {
+		return fmt.Errorf("capacity must be gigabytes,eg., 50Gi")
 	}
 
-	capNum := strings.TrimSuffix(c.flag.Capacity, "Gi")
+	capNum := strings.TrimSuffix(capacity, "Gi")
 	if _, err := strconv.ParseUint(capNum, 10, 32); err != nil {
-		klog.Exit("capacity must be positive integer,eg., 50Gi")
+		return fmt.Errorf("capacity must be positive integer,eg., 50Gi")
 	}
+
+	return nil
 }
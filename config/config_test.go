@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	storageutil "k8s.io/kubectl/pkg/util/storage"
+)
+
+func newTestChecker(flag CommandArg, objects ...runtime.Object) *Checker {
+	return &Checker{
+		flag:   flag,
+		Client: fake.NewSimpleClientset(objects...),
+		Ctx:    context.Background(),
+	}
+}
+
+func TestGetDefaultIngressClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		{name: "no ingressclass"},
+		{
+			name: "no default ingressclass",
+			objects: []runtime.Object{
+				&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}},
+			},
+		},
+		{
+			name: "default ingressclass",
+			objects: []runtime.Object{
+				&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "traefik"}},
+				&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{
+					Name:        "nginx",
+					Annotations: map[string]string{networkingv1beta1.AnnotationIsDefaultIngressClass: "true"},
+				}},
+			},
+			want: "nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestChecker(CommandArg{}, tt.objects...)
+			if got := c.GetDefaultIngressClass(); got != tt.want {
+				t.Errorf("GetDefaultIngressClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIngressAnnotationValue(t *testing.T) {
+	controller := func(args ...string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ingress-nginx-controller-0",
+				Namespace: "ingress-nginx",
+				Labels: map[string]string{
+					"app.kubernetes.io/name":      "ingress-nginx",
+					"app.kubernetes.io/component": "controller",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: IngressContainerName, Args: args}},
+			},
+		}
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx"}}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		{name: "namespace not found"},
+		{name: "no controller pod", objects: []runtime.Object{ns}},
+		{
+			name:    "no ingress-class argument",
+			objects: []runtime.Object{ns, controller("--election-id=ingress-nginx-leader")},
+		},
+		{
+			name:    "ingress-class argument",
+			objects: []runtime.Object{ns, controller("--election-id=ingress-nginx-leader", "--ingress-class=nginx")},
+			want:    "nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestChecker(CommandArg{IngressNamespace: "ingress-nginx"}, tt.objects...)
+			if got := c.GetIngressAnnotationValue(); got != tt.want {
+				t.Errorf("GetIngressAnnotationValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDefaultStorageClass(t *testing.T) {
+	c := newTestChecker(CommandArg{},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "slow"}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{
+			Name:        "standard",
+			Annotations: map[string]string{storageutil.IsDefaultStorageClassAnnotation: "true"},
+		}},
+	)
+
+	got, err := c.GetDefaultStorageClass()
+	if err != nil {
+		t.Fatalf("GetDefaultStorageClass() error = %v", err)
+	}
+	if got != "standard" {
+		t.Errorf("GetDefaultStorageClass() = %q, want %q", got, "standard")
+	}
+
+	c = newTestChecker(CommandArg{}, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "slow"}})
+	if _, err := c.GetDefaultStorageClass(); err == nil {
+		t.Errorf("GetDefaultStorageClass() without default storageclass error = nil")
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// profileKey names the profile used when --profile is not given.
+	profileKey = "profile"
+	// profilesKey holds the named profiles, each a set of settings applied over the top-level ones.
+	profilesKey = "profiles"
+)
+
+// LoadFile reads the settings of a --config file. Keys are flag names and values are what
+// the flag would be given on the command line, a list for repeatable flags. Settings of
+// profile, or of the profile named in the file if it is empty, override the top-level ones.
+func LoadFile(path, profile string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("parse config file [%s]: %v", path, err)
+	}
+
+	profiles := map[string]interface{}{}
+	if v, ok := settings[profilesKey]; ok && v != nil {
+		if profiles, ok = v.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("config file [%s]: %s must be a map of profile names to settings", path, profilesKey)
+		}
+	}
+	if profile == "" {
+		profile, _ = settings[profileKey].(string)
+	}
+	delete(settings, profilesKey)
+	delete(settings, profileKey)
+
+	if profile == "" {
+		return settings, nil
+	}
+	v, ok := profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("profile [%s] not found in config file [%s], available profiles: %s",
+			profile, path, strings.Join(sortedKeys(profiles), ", "))
+	}
+	overrides, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		return nil, fmt.Errorf("config file [%s]: profile [%s] must be a map of settings", path, profile)
+	}
+	for key, value := range overrides {
+		if key == profileKey || key == profilesKey {
+			return nil, fmt.Errorf("config file [%s]: profile [%s] cannot set %s", path, profile, key)
+		}
+		settings[key] = value
+	}
+
+	return settings, nil
+}
+
+// Args converts settings into command line arguments in a stable order, leaving out the
+// flags in given so that the command line wins over the file.
+func Args(settings map[string]interface{}, given map[string]bool) ([]string, error) {
+	var args []string
+	for _, name := range sortedKeys(settings) {
+		if given[name] {
+			continue
+		}
+		flagArgs, err := flagArgs(name, settings[name])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, flagArgs...)
+	}
+
+	return args, nil
+}
+
+// flagArgs converts the setting of flag name into command line arguments.
+func flagArgs(name string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return []string{fmt.Sprintf("--%s=", name)}, nil
+	case bool:
+		if v {
+			return []string{"--" + name}, nil
+		}
+		return []string{"--no-" + name}, nil
+	case []interface{}:
+		var args []string
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return nil, fmt.Errorf("setting [%s] must be a scalar or a list of scalars", name)
+			}
+			args = append(args, fmt.Sprintf("--%s=%s", name, formatValue(item)))
+		}
+		return args, nil
+	case map[string]interface{}:
+		return nil, fmt.Errorf("setting [%s] must be a scalar or a list of scalars", name)
+	default:
+		return []string{fmt.Sprintf("--%s=%s", name, formatValue(v))}, nil
+	}
+}
+
+// formatValue formats a scalar as given on the command line. YAML numbers are decoded as
+// float64, which %v would print in exponent form, e.g. 1e+06, that flags reject.
+func formatValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `
+namespace: function-check
+replicas: 2
+profile: prod-smoke
+profiles:
+  prod-smoke:
+    checks: [service, dns]
+    ready-timeout: 3m
+  full:
+    namespace: function-check-full
+    ingress-tls: true
+    interactive: false
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestLoadFileArgs(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+
+	tests := []struct {
+		name    string
+		profile string
+		given   map[string]bool
+		want    []string
+	}{
+		{
+			name: "default profile",
+			want: []string{"--checks=service", "--checks=dns", "--namespace=function-check",
+				"--ready-timeout=3m", "--replicas=2"},
+		},
+		{
+			name:    "named profile",
+			profile: "full",
+			want: []string{"--ingress-tls", "--no-interactive", "--namespace=function-check-full",
+				"--replicas=2"},
+		},
+		{
+			name:    "command line wins",
+			profile: "full",
+			given:   map[string]bool{"namespace": true, "replicas": true},
+			want:    []string{"--ingress-tls", "--no-interactive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := LoadFile(path, tt.profile)
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			got, err := Args(settings, tt.given)
+			if err != nil {
+				t.Fatalf("Args() error = %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Args() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	path := writeConfigFile(t, testConfigFile)
+	if _, err := LoadFile(path, "missing"); err == nil || !strings.Contains(err.Error(), "available profiles: full, prod-smoke") {
+		t.Errorf("LoadFile() error = %v, want unknown profile", err)
+	}
+
+	path = writeConfigFile(t, "checks: {service: true}\n")
+	settings, err := LoadFile(path, "")
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if _, err := Args(settings, nil); err == nil {
+		t.Errorf("Args() error = nil, want error for a map setting")
+	}
+}
+
+func TestArgsNumbers(t *testing.T) {
+	path := writeConfigFile(t, "replicas: 1000000\nready-timeout: 1000000\nchecks: [12345678, 1.5]\n")
+	settings, err := LoadFile(path, "")
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	got, err := Args(settings, nil)
+	if err != nil {
+		t.Fatalf("Args() error = %v", err)
+	}
+	want := "--checks=12345678 --checks=1.5 --ready-timeout=1000000 --replicas=1000000"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Args() = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RestConfig builds the client config of the cluster to check and describes where it came from.
+// kubeconfig wins over $KUBECONFIG, whose files are merged, which wins over ~/.kube/config.
+// If none of them holds a context the in-cluster config of the pod is used.
+func RestConfig(kubeconfig, context string) (*rest.Config, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	raw, err := loader.RawConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if kubeconfig == "" && len(raw.Contexts) == 0 {
+		rc, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, "", fmt.Errorf("no kubeconfig found in %s and not running in a pod: %v",
+				strings.Join(rules.GetLoadingPrecedence(), ", "), err)
+		}
+		if context != "" {
+			return nil, "", fmt.Errorf("cannot use context [%s] with in-cluster config", context)
+		}
+		return rc, "in-cluster config", nil
+	}
+
+	rc, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if context == "" {
+		context = raw.CurrentContext
+	}
+	files := kubeconfig
+	if files == "" {
+		files = strings.Join(rules.GetLoadingPrecedence(), ":")
+	}
+
+	return rc, fmt.Sprintf("context [%s] of kubeconfig [%s]", context, files), nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKubeconfig(t *testing.T, dir, name string, current bool) string {
+	t.Helper()
+
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: https://%[1]s.example.com:6443
+users:
+- name: %[1]s
+  user:
+    token: token
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+`, name)
+	if current {
+		content += "current-context: " + name + "\n"
+	}
+
+	path := filepath.Join(dir, name+".yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestRestConfig(t *testing.T) {
+	dir := t.TempDir()
+	prod := writeKubeconfig(t, dir, "prod", true)
+	staging := writeKubeconfig(t, dir, "staging", false)
+	t.Setenv("KUBECONFIG", strings.Join([]string{prod, staging}, string(os.PathListSeparator)))
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+		context    string
+		wantHost   string
+		wantSource string
+		wantErr    bool
+	}{
+		{name: "current context of merged files", wantHost: "https://prod.example.com:6443", wantSource: "context [prod]"},
+		{name: "context of second file", context: "staging", wantHost: "https://staging.example.com:6443"},
+		{name: "explicit kubeconfig", kubeconfig: staging, context: "staging",
+			wantHost: "https://staging.example.com:6443", wantSource: "kubeconfig [" + staging + "]"},
+		{name: "explicit kubeconfig ignores KUBECONFIG", kubeconfig: staging, context: "prod", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, source, err := RestConfig(tt.kubeconfig, tt.context)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if rc.Host != tt.wantHost {
+				t.Errorf("RestConfig() host = %s, want %s", rc.Host, tt.wantHost)
+			}
+			if !strings.Contains(source, tt.wantSource) {
+				t.Errorf("RestConfig() source = %q, want it to contain %q", source, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	timeFormat   = "20060102-150405"
+	bundlePrefix = "function-checker-"
+)
+
+// Collector captures the objects created by the checker, their events and container
+// logs, so a failed run can be investigated after cleanup removed everything.
+type Collector struct {
+	Client    kubernetes.Interface
+	Namespace string
+	// Selector selects the objects created by the checker.
+	Selector string
+	// Container is the container whose logs are captured from every selected pod.
+	Container string
+}
+
+// Collect writes the bundle into a timestamped directory below dir, or into a
+// gzipped tarball next to it if tarball is set, and returns the path written.
+// Errors of single captures are aggregated and do not stop the collection.
+func (c *Collector) Collect(ctx context.Context, dir string, tarball bool) (string, error) {
+	bundle := filepath.Join(dir, bundlePrefix+time.Now().Format(timeFormat))
+	if err := os.MkdirAll(filepath.Join(bundle, "logs"), 0755); err != nil {
+		return "", err
+	}
+
+	klog.Infof("Collect diagnostics of namespace [%s] into [%s]", c.Namespace, bundle)
+	collectErr := c.collect(ctx, bundle)
+
+	if !tarball {
+		return bundle, collectErr
+	}
+
+	archive := bundle + ".tar.gz"
+	if err := writeTarball(bundle, archive); err != nil {
+		return bundle, utilerrors.NewAggregate([]error{collectErr, err})
+	}
+	if err := os.RemoveAll(bundle); err != nil {
+		return archive, utilerrors.NewAggregate([]error{collectErr, err})
+	}
+
+	return archive, collectErr
+}
+
+// Prune removes the oldest bundles in dir, directories and tarballs alike, so that at most
+// keep of them are left.
+func Prune(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// The timestamp in the name sorts the bundles oldest first, as ReadDir sorts by name.
+	var bundles []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), bundlePrefix) {
+			bundles = append(bundles, e.Name())
+		}
+	}
+	if len(bundles) <= keep {
+		return nil
+	}
+
+	var allErrs []error
+	for _, name := range bundles[:len(bundles)-keep] {
+		klog.Infof("Remove diagnostics [%s]", filepath.Join(dir, name))
+		allErrs = append(allErrs, os.RemoveAll(filepath.Join(dir, name)))
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (c *Collector) collect(ctx context.Context, bundle string) error {
+	var allErrs []error
+	opts := metav1.ListOptions{LabelSelector: c.Selector}
+	involved := map[string]bool{}
+
+	record := func(kind string, names ...string) {
+		for _, name := range names {
+			involved[kind+"/"+name] = true
+		}
+	}
+
+	stsList, err := c.Client.AppsV1().StatefulSets(c.Namespace).List(ctx, opts)
+	if err == nil {
+		for _, item := range stsList.Items {
+			record("StatefulSet", item.Name)
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "statefulsets.yaml", stsList, err))
+
+	pods, err := c.Client.CoreV1().Pods(c.Namespace).List(ctx, opts)
+	if err == nil {
+		for _, item := range pods.Items {
+			record("Pod", item.Name)
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "pods.yaml", pods, err))
+
+	pvcs, err := c.Client.CoreV1().PersistentVolumeClaims(c.Namespace).List(ctx, opts)
+	if err == nil {
+		for _, item := range pvcs.Items {
+			record("PersistentVolumeClaim", item.Name)
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "persistentvolumeclaims.yaml", pvcs, err))
+
+	svcs, err := c.Client.CoreV1().Services(c.Namespace).List(ctx, opts)
+	endpoints := &corev1.EndpointsList{}
+	if err == nil {
+		for _, item := range svcs.Items {
+			record("Service", item.Name)
+			ep, err := c.Client.CoreV1().Endpoints(c.Namespace).Get(ctx, item.Name, metav1.GetOptions{})
+			if err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+			record("Endpoints", item.Name)
+			endpoints.Items = append(endpoints.Items, *ep)
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "services.yaml", svcs, err))
+	allErrs = append(allErrs, writeObject(bundle, "endpoints.yaml", endpoints, nil))
+
+	ings, err := c.Client.NetworkingV1().Ingresses(c.Namespace).List(ctx, opts)
+	if err == nil {
+		for _, item := range ings.Items {
+			record("Ingress", item.Name)
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "ingresses.yaml", ings, err))
+
+	events, err := c.Client.CoreV1().Events(c.Namespace).List(ctx, metav1.ListOptions{})
+	related := &corev1.EventList{}
+	if err == nil {
+		for _, event := range events.Items {
+			if involved[event.InvolvedObject.Kind+"/"+event.InvolvedObject.Name] {
+				related.Items = append(related.Items, event)
+			}
+		}
+	}
+	allErrs = append(allErrs, writeObject(bundle, "events.yaml", related, err))
+
+	if pods != nil {
+		for _, pod := range pods.Items {
+			allErrs = append(allErrs, c.collectLogs(ctx, bundle, &pod))
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (c *Collector) collectLogs(ctx context.Context, bundle string, pod *corev1.Pod) error {
+	var allErrs []error
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != c.Container {
+			continue
+		}
+
+		allErrs = append(allErrs, c.writeLogs(ctx, filepath.Join(bundle, "logs", pod.Name+".log"), pod.Name, false))
+		if cs.RestartCount > 0 {
+			allErrs = append(allErrs, c.writeLogs(ctx, filepath.Join(bundle, "logs", pod.Name+".previous.log"), pod.Name, true))
+		}
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+func (c *Collector) writeLogs(ctx context.Context, path, podName string, previous bool) error {
+	stream, err := c.Client.CoreV1().Pods(c.Namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: c.Container,
+		Previous:  previous,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("logs of pod [%s]: %v", podName, err)
+	}
+	defer stream.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, stream); err != nil {
+		f.Close()
+		return fmt.Errorf("logs of pod [%s]: %v", podName, err)
+	}
+	return f.Close()
+}
+
+// writeObject writes obj as YAML, or the error which prevented listing it.
+func writeObject(bundle, name string, obj runtime.Object, listErr error) error {
+	path := filepath.Join(bundle, name)
+	if listErr != nil {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("# error: %v\n", listErr)), 0644); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: %v", strings.TrimSuffix(name, ".yaml"), listErr)
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeTarball(src, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	base := filepath.Dir(src)
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,174 @@
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "function-check"
+
+var testLabels = map[string]string{"component": "k8s-function-checker"}
+
+func newTestCollector() *Collector {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sts-0", Namespace: testNamespace, Labels: testLabels},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "nginx", RestartCount: 2},
+		}},
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: testNamespace, Labels: testLabels}}
+	ep := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: testNamespace}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: testNamespace}}
+	events := []*corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "sts-0.1", Namespace: testNamespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "sts-0"},
+			Message:        "Back-off pulling image",
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "other.1", Namespace: testNamespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "other"},
+			Message:        "unrelated event",
+		},
+	}
+
+	client := fake.NewSimpleClientset(pod, svc, ep, other, events[0], events[1])
+	return &Collector{
+		Client:    client,
+		Namespace: testNamespace,
+		Selector:  "component=k8s-function-checker",
+		Container: "nginx",
+	}
+}
+
+func TestCollect(t *testing.T) {
+	c := newTestCollector()
+
+	bundle, err := c.Collect(context.Background(), t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"statefulsets.yaml", "pods.yaml", "persistentvolumeclaims.yaml", "services.yaml",
+		"endpoints.yaml", "ingresses.yaml", "events.yaml", "logs/sts-0.log", "logs/sts-0.previous.log",
+	} {
+		if _, err := os.Stat(filepath.Join(bundle, name)); err != nil {
+			t.Errorf("%s not collected: %v", name, err)
+		}
+	}
+
+	pods, _ := os.ReadFile(filepath.Join(bundle, "pods.yaml"))
+	if !strings.Contains(string(pods), "sts-0") || strings.Contains(string(pods), "other") {
+		t.Errorf("pods.yaml should only contain selected pods:\n%s", pods)
+	}
+	events, _ := os.ReadFile(filepath.Join(bundle, "events.yaml"))
+	if !strings.Contains(string(events), "Back-off pulling image") || strings.Contains(string(events), "unrelated event") {
+		t.Errorf("events.yaml should only contain events of selected objects:\n%s", events)
+	}
+	endpoints, _ := os.ReadFile(filepath.Join(bundle, "endpoints.yaml"))
+	if !strings.Contains(string(endpoints), "name: svc") {
+		t.Errorf("endpoints.yaml should contain endpoints of selected services:\n%s", endpoints)
+	}
+}
+
+func TestCollectTarball(t *testing.T) {
+	c := newTestCollector()
+	dir := t.TempDir()
+
+	archive, err := c.Collect(context.Background(), dir, true)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !strings.HasSuffix(archive, ".tar.gz") {
+		t.Fatalf("Collect() = %q, want a tarball", archive)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(archive, ".tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("bundle directory should be removed after packing, stat error = %v", err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+
+	base := filepath.Base(strings.TrimSuffix(archive, ".tar.gz"))
+	for _, want := range []string{base + "/pods.yaml", base + "/logs/sts-0.log"} {
+		i := sort.SearchStrings(names, want)
+		if i == len(names) || names[i] != want {
+			t.Errorf("tarball misses %s, has %v", want, names)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"function-checker-20240101-100000",
+		"function-checker-20240101-110000.tar.gz",
+		"function-checker-20240101-120000",
+		"function-checker-20240101-130000.tar.gz",
+		"unrelated",
+	} {
+		if strings.HasSuffix(name, ".tar.gz") || name == "unrelated" {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, name, "logs"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Prune(dir, 2); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	want := []string{"function-checker-20240101-120000", "function-checker-20240101-130000.tar.gz", "unrelated"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Prune() left %v, want %v", got, want)
+	}
+
+	if err := Prune(filepath.Join(dir, "missing"), 2); err != nil {
+		t.Errorf("Prune() of a missing directory error = %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	name = "k8s-function-checker"
+
+	// CronJobV1 is served since kubernetes 1.21, CronJobV1beta1 was removed in 1.25.
+	CronJobV1      = "batch/v1"
+	CronJobV1beta1 = "batch/v1beta1"
+)
+
+var (
+	installLabels = map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/managed-by": name + "-install",
+	}
+
+	// clusterRules cover the cluster scoped reads of flag verification and of the checks.
+	clusterRules = []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}},
+		{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"storageclasses"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingressclasses"}, Verbs: []string{"list"}},
+	}
+	// namespaceRules cover the resources created, probed and collected as diagnostics in the
+	// namespace of the checks.
+	namespaceRules = []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps", "secrets"}, Verbs: []string{"create", "delete"}},
+		{APIGroups: []string{""}, Resources: []string{"services"}, Verbs: []string{"create", "delete", "get", "list"}},
+		{APIGroups: []string{""}, Resources: []string{"endpoints"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"list"}},
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"create", "delete", "get", "list", "watch"}},
+		{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"create"}},
+		{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims"}, Verbs: []string{"get", "list", "deletecollection"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"statefulsets"}, Verbs: []string{"create", "delete", "get", "list", "watch"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, Verbs: []string{"create", "delete"}},
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"ingresses"}, Verbs: []string{"create", "delete", "get", "list"}},
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"create", "delete"}},
+	}
+	// ingressRules cover the discovery of the ingress controller.
+	ingressRules = []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"list"}},
+	}
+
+	resources = map[string]schema.GroupVersionResource{
+		"ServiceAccount":     {Version: "v1", Resource: "serviceaccounts"},
+		"ClusterRole":        rbacv1.SchemeGroupVersion.WithResource("clusterroles"),
+		"ClusterRoleBinding": rbacv1.SchemeGroupVersion.WithResource("clusterrolebindings"),
+		"Role":               rbacv1.SchemeGroupVersion.WithResource("roles"),
+		"RoleBinding":        rbacv1.SchemeGroupVersion.WithResource("rolebindings"),
+		"CronJob":            {Group: "batch", Resource: "cronjobs"},
+	}
+)
+
+// Options describe an installation of the checker running on a schedule inside the cluster.
+type Options struct {
+	// Namespace holds the installed objects and the resources created by the checks.
+	Namespace        string
+	IngressNamespace string
+	// Image is the image of the checker itself.
+	Image    string
+	Schedule string
+	// Args are appended to the run command of the checker.
+	Args []string
+	// CronJobAPIVersion is CronJobV1 or CronJobV1beta1.
+	CronJobAPIVersion string
+}
+
+// CronJobAPIVersion returns the newest CronJob API version served by the cluster.
+func CronJobAPIVersion(client discovery.DiscoveryInterface) (string, error) {
+	for _, gv := range []string{CronJobV1, CronJobV1beta1} {
+		list, err := client.ServerResourcesForGroupVersion(gv)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, r := range list.APIResources {
+			if r.Name == "cronjobs" {
+				return gv, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("cluster serves neither %s nor %s cronjobs", CronJobV1, CronJobV1beta1)
+}
+
+// Render builds the objects of the installation in the order they are applied.
+func Render(opts Options) ([]*unstructured.Unstructured, error) {
+	clusterName := fmt.Sprintf("%s-%s", name, opts.Namespace)
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: opts.Namespace}}
+	meta := func(name, namespace string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: installLabels}
+	}
+
+	objects := []runtime.Object{
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: meta(name, opts.Namespace),
+		},
+	}
+	objects = append(objects,
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: meta(clusterName, ""),
+			Rules:      clusterRules,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: meta(clusterName, ""),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterName},
+			Subjects:   subjects,
+		})
+	type namespaceRole struct {
+		namespace string
+		rules     []rbacv1.PolicyRule
+	}
+	roles := []namespaceRole{
+		{namespace: opts.Namespace, rules: namespaceRules},
+		{namespace: opts.IngressNamespace, rules: ingressRules},
+	}
+	if opts.IngressNamespace == opts.Namespace {
+		roles = []namespaceRole{{
+			namespace: opts.Namespace,
+			rules:     append(append([]rbacv1.PolicyRule{}, namespaceRules...), ingressRules...),
+		}}
+	}
+	for _, role := range roles {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: meta(name, role.namespace),
+				Rules:      role.rules,
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: meta(name, role.namespace),
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+				Subjects:   subjects,
+			})
+	}
+	objects = append(objects, cronJob(opts))
+
+	var rendered []*unstructured.Unstructured
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(u.Object, "status")
+		rendered = append(rendered, u)
+	}
+
+	return rendered, nil
+}
+
+// cronJob builds the CronJob as batch/v1beta1, whose spec batch/v1 shares.
+func cronJob(opts Options) *batchv1beta1.CronJob {
+	historyLimit := int32(3)
+	backoffLimit := int32(0)
+	args := []string{"run", "--namespace=" + opts.Namespace, "--ingress-namespace=" + opts.IngressNamespace,
+		"--no-interactive", "--output=json"}
+	args = append(args, opts.Args...)
+
+	return &batchv1beta1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: opts.CronJobAPIVersion, Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opts.Namespace, Labels: installLabels},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   opts.Schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: installLabels},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: installLabels},
+						Spec: corev1.PodSpec{
+							ServiceAccountName: name,
+							RestartPolicy:      corev1.RestartPolicyNever,
+							Containers: []corev1.Container{{
+								Name:  name,
+								Image: opts.Image,
+								Args:  args,
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Write prints objects as a multi-document YAML stream.
+func Write(w io.Writer, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply creates objects, or updates them if they already exist.
+func Apply(ctx context.Context, client dynamic.Interface, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		ri, err := resourceFor(client, obj)
+		if err != nil {
+			return err
+		}
+
+		existing, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			_, err = ri.Create(ctx, obj, metav1.CreateOptions{})
+		case err == nil:
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			_, err = ri.Update(ctx, obj, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			klog.Infoln(err.Error())
+			return fmt.Errorf("apply %s [%s]: %v", obj.GetKind(), formatName(obj), err)
+		}
+		klog.Infof("%s [%s] applied", obj.GetKind(), formatName(obj))
+	}
+
+	return nil
+}
+
+// Delete deletes objects in the reverse order of Apply, objects which do not exist are ignored.
+func Delete(ctx context.Context, client dynamic.Interface, objects []*unstructured.Unstructured) error {
+	for i := len(objects) - 1; i >= 0; i-- {
+		obj := objects[i]
+		ri, err := resourceFor(client, obj)
+		if err != nil {
+			return err
+		}
+
+		propagation := metav1.DeletePropagationBackground
+		err = ri.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			klog.Infoln(err.Error())
+			return fmt.Errorf("delete %s [%s]: %v", obj.GetKind(), formatName(obj), err)
+		}
+		klog.Infof("%s [%s] deleted", obj.GetKind(), formatName(obj))
+	}
+
+	return nil
+}
+
+func resourceFor(client dynamic.Interface, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvr, ok := resources[obj.GetKind()]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %s", obj.GetKind())
+	}
+	gvr.Version = obj.GroupVersionKind().Version
+
+	if obj.GetNamespace() == "" {
+		return client.Resource(gvr), nil
+	}
+	return client.Resource(gvr).Namespace(obj.GetNamespace()), nil
+}
+
+func formatName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -0,0 +1,175 @@
+package install
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func testOptions() Options {
+	return Options{
+		Namespace:         "function-check",
+		IngressNamespace:  "ingress-nginx",
+		Image:             "example.com/k8s-function-checker:v1",
+		Schedule:          "*/30 * * * *",
+		Args:              []string{"--checks=dns"},
+		CronJobAPIVersion: CronJobV1,
+	}
+}
+
+func kinds(objects []*unstructured.Unstructured) string {
+	var names []string
+	for _, obj := range objects {
+		names = append(names, obj.GetKind()+":"+formatName(obj))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestRender(t *testing.T) {
+	objects, err := Render(testOptions())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := "ServiceAccount:function-check/k8s-function-checker," +
+		"ClusterRole:k8s-function-checker-function-check," +
+		"ClusterRoleBinding:k8s-function-checker-function-check," +
+		"Role:function-check/k8s-function-checker,RoleBinding:function-check/k8s-function-checker," +
+		"Role:ingress-nginx/k8s-function-checker,RoleBinding:ingress-nginx/k8s-function-checker," +
+		"CronJob:function-check/k8s-function-checker"
+	if got := kinds(objects); got != want {
+		t.Errorf("Render() = %s, want %s", got, want)
+	}
+
+	cronJob := objects[len(objects)-1]
+	if cronJob.GetAPIVersion() != CronJobV1 {
+		t.Errorf("CronJob apiVersion = %s, want %s", cronJob.GetAPIVersion(), CronJobV1)
+	}
+	containers, _, _ := unstructured.NestedSlice(cronJob.Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+	args, _, _ := unstructured.NestedStringSlice(containers[0].(map[string]interface{}), "args")
+	if got := strings.Join(args, " "); got != "run --namespace=function-check --ingress-namespace=ingress-nginx "+
+		"--no-interactive --output=json --checks=dns" {
+		t.Errorf("CronJob args = %s", got)
+	}
+}
+
+func TestRenderSameNamespace(t *testing.T) {
+	opts := testOptions()
+	opts.IngressNamespace = opts.Namespace
+	objects, err := Render(opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	var roles int
+	for _, obj := range objects {
+		if obj.GetKind() == "Role" {
+			roles++
+			rules, _, _ := unstructured.NestedSlice(obj.Object, "rules")
+			if len(rules) != len(namespaceRules)+len(ingressRules) {
+				t.Errorf("Role has %d rules, want %d", len(rules), len(namespaceRules)+len(ingressRules))
+			}
+		}
+	}
+	if roles != 1 {
+		t.Errorf("Render() created %d roles, want 1", roles)
+	}
+}
+
+func TestApplyDelete(t *testing.T) {
+	ctx := context.Background()
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	objects, err := Render(testOptions())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if err := Apply(ctx, client, objects); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	// Applying again updates the existing objects.
+	objects[len(objects)-1].Object["spec"].(map[string]interface{})["schedule"] = "0 * * * *"
+	if err := Apply(ctx, client, objects); err != nil {
+		t.Fatalf("second Apply() error = %v", err)
+	}
+	cronJobs := schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	got, err := client.Resource(cronJobs).Namespace("function-check").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("CronJob not found after Apply: %v", err)
+	}
+	if schedule, _, _ := unstructured.NestedString(got.Object, "spec", "schedule"); schedule != "0 * * * *" {
+		t.Errorf("CronJob schedule = %s, want it updated", schedule)
+	}
+
+	if err := Delete(ctx, client, objects); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := client.Resource(cronJobs).Namespace("function-check").Get(ctx, name, metav1.GetOptions{}); err == nil {
+		t.Errorf("CronJob still exists after Delete")
+	}
+	// Deleting what does not exist is not an error.
+	if err := Delete(ctx, client, objects); err != nil {
+		t.Errorf("second Delete() error = %v", err)
+	}
+}
+
+func TestCronJobAPIVersion(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	// Before kubernetes 1.21 batch/v1 only serves jobs.
+	client.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: CronJobV1,
+			APIResources: []metav1.APIResource{{Name: "jobs", Kind: "Job", Namespaced: true}},
+		},
+		{
+			GroupVersion: CronJobV1beta1,
+			APIResources: []metav1.APIResource{{Name: "cronjobs", Kind: "CronJob", Namespaced: true}},
+		},
+	}
+
+	got, err := CronJobAPIVersion(client.Discovery())
+	if err != nil || got != CronJobV1beta1 {
+		t.Errorf("CronJobAPIVersion() = %s, %v, want %s", got, err, CronJobV1beta1)
+	}
+}
+
+func TestRenderClusterRole(t *testing.T) {
+	objects, err := Render(testOptions())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	// Only the reads of clusterRules are granted outside the namespaces of the installation.
+	for _, obj := range objects {
+		if obj.GetKind() != "ClusterRole" {
+			continue
+		}
+		clusterRole := &rbacv1.ClusterRole{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, clusterRole); err != nil {
+			t.Fatalf("FromUnstructured() error = %v", err)
+		}
+		for _, rule := range clusterRole.Rules {
+			for _, verb := range rule.Verbs {
+				switch verb {
+				case "get", "list":
+				default:
+					t.Errorf("ClusterRole [%s] grants %s on %v cluster wide", clusterRole.Name, verb, rule.Resources)
+				}
+			}
+			for _, resource := range rule.Resources {
+				switch resource {
+				case "rolebindings", "clusterroles", "clusterrolebindings", "pods/exec", "secrets":
+					t.Errorf("ClusterRole [%s] grants %s cluster wide", clusterRole.Name, resource)
+				}
+			}
+		}
+	}
+}
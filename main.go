@@ -1,127 +1,953 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/tiggoins/function-checker/check"
+	"github.com/tiggoins/function-checker/cleanup"
 	"github.com/tiggoins/function-checker/config"
+	"github.com/tiggoins/function-checker/diagnostics"
+	"github.com/tiggoins/function-checker/install"
+	"github.com/tiggoins/function-checker/report"
 	"github.com/tiggoins/function-checker/resource"
+	"github.com/tiggoins/function-checker/serve"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/klog/v2"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
+// Exit codes of the process, so scripts can branch on the outcome of a run.
+const (
+	ExitOK            = 0
+	ExitCheckFailed   = 1
+	ExitSetupFailed   = 2
+	ExitCleanupFailed = 3
+)
+
+const cleanupTimeout = time.Duration(2) * time.Minute
+
 func main() {
 	app := kingpin.New("k8s-function-checker", "create configmap/statefulset/service/ingress "+
 		"to test if k8s works fine.").Version("v1.0.0-alpha0")
 
 	cfg := config.CommandArg{}
+	app.Flag("config", "YAML file of settings keyed by flag name, of the command run as well, flags "+
+		"given on the command line win").
+		StringVar(&cfg.ConfigFile)
+	app.Flag("profile", "Named profile of the config file to apply over its top-level settings").
+		StringVar(&cfg.Profile)
+	app.Flag("kubeconfig", "Kubeconfig file, $KUBECONFIG or ~/.kube/config is used if not set "+
+		"and the in-cluster config if none of them exists").StringVar(&cfg.Kubeconfig)
+	app.Flag("context", "Context of the kubeconfig to use, the current context if not set").
+		StringVar(&cfg.Context)
 	app.Flag("namespace", "Namespace to test kubernetes function.").Default("default").
 		Short('n').StringVar(&cfg.Namespace)
+	app.Flag("ephemeral-namespace", "Create a namespace for every run instead of using --namespace, "+
+		"it is deleted with everything in it at the end of the run").BoolVar(&cfg.EphemeralNamespace)
+	app.Flag("pod-security-level", "Pod Security Admission level enforced in the namespace created by "+
+		"--ephemeral-namespace("+strings.Join(resource.PodSecurityLevels, "|")+")").
+		EnumVar(&cfg.PodSecurityLevel, resource.PodSecurityLevels...)
+	app.Flag("namespace-role", "ClusterRole bound inside the namespace created by --ephemeral-namespace, "+
+		"so that what the checks need is granted in no other namespace").StringVar(&cfg.NamespaceRole)
+	app.Flag("namespace-role-serviceaccount", "ServiceAccount(namespace:name) the --namespace-role is bound to").
+		StringVar(&cfg.NamespaceRoleSA)
+	app.Flag("namespace-delete-timeout", "Time to wait for the namespace created by --ephemeral-namespace "+
+		"to be finalized").Default("5m").DurationVar(&cfg.NamespaceTimeout)
 	app.Flag("ingress-namespace", "Namespace which ingress-nginx located").
-		Short('i').Required().StringVar(&cfg.IngressNamespace)
+		Short('i').StringVar(&cfg.IngressNamespace)
 	app.Flag("storageclass", "Storagclass to request storagce").Short('s').
 		StringVar(&cfg.Storageclass)
 	app.Flag("capacity", "Capacity to create persistencevolume").Short('c').
 		Default("50Gi").StringVar(&cfg.Capacity)
-	app.Flag("host", "Host to use in ingress").Default("nginx-test.js.sgcc.com.cn").
+	app.Flag("host", "Domain of the ingress host, the run ID is prepended to it as <run id>.<host>, so a wildcard DNS record *.<host> must resolve to the ingress controller").Default("nginx-test.js.sgcc.com.cn").
 		Short('h').StringVar(&cfg.Domain)
+	app.Flag("ready-timeout", "Time to wait for the statefulset to become ready").
+		Default("5m").DurationVar(&cfg.ReadyTimeout)
+	app.Flag("ingress-timeout", "Time to wait for the ingress controller to serve the page and the certificate").
+		Default(resource.IngressTimeout.String()).DurationVar(&resource.IngressTimeout)
+	app.Flag("ingress-status-timeout", "Time to wait for the ingress controller to publish an address in the "+
+		"ingress status, the address of the controller service is used otherwise").
+		Default(resource.IngressStatusTimeout.String()).DurationVar(&resource.IngressStatusTimeout)
+	app.Flag("loadbalancer-timeout", "Time to wait for a load balancer address, "+
+		"the service-loadbalancer check is skipped if none is assigned").Default("2m").DurationVar(&cfg.LBTimeout)
+	app.Flag("cluster-domain", "DNS domain of the cluster").Default("cluster.local").
+		StringVar(&cfg.ClusterDomain)
+	app.Flag("dns-external-name", "External name to resolve in dns check, empty to skip it").
+		Default("kubernetes.io").StringVar(&cfg.DNSExternalName)
+	app.Flag("tolerate-control-plane", "Run the node-health probe on control-plane nodes as well").
+		BoolVar(&cfg.TolerateMaster)
+	app.Flag("ingress-endpoint", "Address(host:port) of ingress controller, "+
+		"discovered from ingress status or controller service if not set").StringVar(&cfg.IngressEndpoint)
+	app.Flag("ingress-tls", "Serve the ingress over HTTPS with a certificate signed by a generated CA "+
+		"and verify TLS termination").BoolVar(&cfg.IngressTLS)
+	app.Flag("ingress-tls-endpoint", "Address(host:port) of ingress controller for HTTPS, "+
+		"discovered the same way as --ingress-endpoint if not set").StringVar(&cfg.TLSEndpoint)
+	app.Flag("interactive", "Ask user to verify ingress from browser instead of probing it").
+		BoolVar(&cfg.Interactive)
+	app.Flag("dry-run", "Print the resources a run creates as YAML(client) or submit them with server-side "+
+		"dry run(server) to exercise admission, nothing is persisted and no check runs").
+		EnumVar(&cfg.DryRun, config.DryRunClient, config.DryRunServer)
+	app.Flag("keep", "Leave the resources of a run in place to debug it("+
+		strings.Join([]string{config.KeepNever, config.KeepOnFailure, config.KeepAlways}, "|")+
+		"), they are removed later by the cleanup command").Default(config.KeepNever).
+		EnumVar(&cfg.Keep, config.KeepNever, config.KeepOnFailure, config.KeepAlways)
+	app.Flag("image", "Image of every pod created, it must provide nginx, curl and getent").
+		Default(resource.Image).StringVar(&resource.Image)
+	app.Flag("replicas", "Replicas of the statefulset").
+		Default(strconv.Itoa(int(resource.Replicas))).Int32Var(&resource.Replicas)
+	app.Flag("page", "Content of the page served by the statefulset").
+		Default(resource.Page).StringVar(&resource.Page)
+
+	app.Flag("output", "Print report of all checks to stdout in the given format(json|yaml)").
+		Short('o').EnumVar(&cfg.Output, report.FormatJSON, report.FormatYAML)
+	app.Flag("junit-file", "Write report of all checks to the file in JUnit XML format").
+		StringVar(&cfg.JUnitFile)
+
+	app.Flag("diagnostics-dir", "Directory to collect diagnostics into when a check fails, empty to disable").
+		Default("function-checker-diagnostics").StringVar(&cfg.DiagnosticsDir)
+	app.Flag("diagnostics-tar", "Pack collected diagnostics into a gzipped tarball").
+		BoolVar(&cfg.DiagnosticsTar)
+	app.Flag("checks", "Checks to run, comma separated or repeated, all checks run if not set").
+		StringsVar(&cfg.Checks)
+	app.Flag("skip-checks", "Checks to skip, comma separated or repeated").
+		StringsVar(&cfg.SkipChecks)
+
+	runCmd := app.Command("run", "Create resources and run checks against the cluster.").Default()
+	listChecksCmd := app.Command("list-checks", "List available checks.")
+
+	var installOpts install.Options
+	var render bool
+	installCmd := app.Command("install", "Install a CronJob running the checks on a schedule inside the cluster, "+
+		"with a ServiceAccount allowed exactly what the checks need.")
+	installCmd.Flag("schedule", "Cron schedule of the checks").Default("0 * * * *").StringVar(&installOpts.Schedule)
+	installCmd.Flag("checker-image", "Image of k8s-function-checker run by the CronJob").Required().
+		StringVar(&installOpts.Image)
+	installCmd.Flag("render", "Print the objects as YAML instead of applying them, the CronJob is rendered as "+
+		install.CronJobV1).BoolVar(&render)
+	installCmd.Arg("run-args", "Flags appended to the run command of the CronJob, given after --").
+		StringsVar(&installOpts.Args)
+	uninstallCmd := app.Command("uninstall", "Delete what install created.")
+
+	var olderThan time.Duration
+	var runID string
+	var yes bool
+	cleanupCmd := app.Command("cleanup", "List the objects left behind in every namespace by runs which "+
+		"could not clean up, e.g. because they were killed, and delete them with --yes.")
+	cleanupCmd.Flag("older-than", "Only delete objects created longer than this ago, so that runs in "+
+		"progress are left alone. Not applied with --run-id").Default("1h").DurationVar(&olderThan)
+	cleanupCmd.Flag("run-id", "Only delete the objects of this run, e.g. those kept with --keep").
+		StringVar(&runID)
+	cleanupCmd.Flag("yes", "Delete the objects listed instead of only listing them").BoolVar(&yes)
+
+	var listen string
+	var interval time.Duration
+	serveCmd := app.Command("serve", "Run checks on an interval and serve /metrics, /healthz and /results.")
+	serveCmd.Flag("listen", "Address to serve on").Default(":8080").StringVar(&listen)
+	serveCmd.Flag("interval", "Time between the start of two runs").Default("15m").DurationVar(&interval)
+	var retain int
+	serveCmd.Flag("diagnostics-retain", "Number of diagnostics bundles left in --diagnostics-dir, the oldest "+
+		"are removed after each run").Default("10").IntVar(&retain)
+
+	args, err := withConfigFile(app, os.Args[1:])
+	if err != nil {
+		app.Errorf("%s", err.Error())
+		os.Exit(ExitSetupFailed)
+	}
+	command, err := app.Parse(args)
+	if err != nil {
+		app.Errorf("%s, try --help", err.Error())
+		os.Exit(ExitSetupFailed)
+	}
+	if resource.Replicas < 1 {
+		app.Errorf("--replicas must be at least 1")
+		os.Exit(ExitSetupFailed)
+	}
+	if cfg.DryRun != "" && command != runCmd.FullCommand() {
+		app.Errorf("--dry-run is only supported by run")
+		os.Exit(ExitSetupFailed)
+	}
+	if cfg.DryRun == config.DryRunServer && cfg.EphemeralNamespace {
+		app.Errorf("--dry-run=server cannot submit into the namespace of --ephemeral-namespace, which is not created")
+		os.Exit(ExitSetupFailed)
+	}
+	if cfg.DryRun == config.DryRunClient && cfg.Output != "" {
+		app.Errorf("--dry-run=client prints the resources to stdout, it cannot be combined with --output")
+		os.Exit(ExitSetupFailed)
+	}
+	if cfg.PodSecurityLevel != "" && !cfg.EphemeralNamespace {
+		app.Errorf("--pod-security-level requires --ephemeral-namespace")
+		os.Exit(ExitSetupFailed)
+	}
+	if (cfg.NamespaceRole != "" || cfg.NamespaceRoleSA != "") && !cfg.EphemeralNamespace {
+		app.Errorf("--namespace-role requires --ephemeral-namespace")
+		os.Exit(ExitSetupFailed)
+	}
+	if (cfg.NamespaceRole == "") != (cfg.NamespaceRoleSA == "") {
+		app.Errorf("--namespace-role and --namespace-role-serviceaccount must be given together")
+		os.Exit(ExitSetupFailed)
+	}
+	if sa := strings.Split(cfg.NamespaceRoleSA, ":"); cfg.NamespaceRoleSA != "" && (len(sa) != 2 || sa[0] == "" || sa[1] == "") {
+		app.Errorf("--namespace-role-serviceaccount must be namespace:name")
+		os.Exit(ExitSetupFailed)
+	}
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	switch command {
+	case installCmd.FullCommand():
+		os.Exit(runInstall(cfg, installOpts, render))
+	case uninstallCmd.FullCommand():
+		os.Exit(runUninstall(cfg))
+	case cleanupCmd.FullCommand():
+		os.Exit(runCleanup(cfg, olderThan, runID, yes))
+	case listChecksCmd.FullCommand():
+		listChecks()
+	case runCmd.FullCommand(), serveCmd.FullCommand():
+		checks, err := check.Select(cfg.Checks, cfg.SkipChecks)
+		if err != nil {
+			app.Errorf("%s", err.Error())
+			os.Exit(ExitSetupFailed)
+		}
+		if command == serveCmd.FullCommand() {
+			// Every run would leave its resources behind until the cluster is full of them.
+			if cfg.Keep != config.KeepNever {
+				app.Errorf("--keep=%s cannot be used with serve", cfg.Keep)
+				os.Exit(ExitSetupFailed)
+			}
+			if retain < 0 {
+				app.Errorf("--diagnostics-retain must not be negative")
+				os.Exit(ExitSetupFailed)
+			}
+			os.Exit(runServe(cfg, checks, listen, interval, retain))
+		}
+		os.Exit(runChecks(cfg, checks))
+	}
+}
 
-	checker := config.NewChecker(cfg)
+// withConfigFile adds the settings of the --config file to args as flags, so that flags
+// given in args win over the file and the file wins over the defaults. Settings may be
+// flags of the selected command as well, flags of other commands are rejected.
+func withConfigFile(app *kingpin.Application, args []string) ([]string, error) {
+	pc, err := app.ParseContext(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s, try --help", err.Error())
+	}
+
+	given := map[string]bool{}
+	var path, profile string
+	for _, el := range pc.Elements {
+		flag, ok := el.Clause.(*kingpin.FlagClause)
+		if !ok {
+			continue
+		}
+		name := flag.Model().Name
+		given[name] = true
+		switch {
+		case name == "config" && el.Value != nil:
+			path = *el.Value
+		case name == "profile" && el.Value != nil:
+			profile = *el.Value
+		}
+	}
+	if path == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("--profile requires --config")
+		}
+		return args, nil
+	}
+
+	settings, err := config.LoadFile(path, profile)
+	if err != nil {
+		return nil, err
+	}
+	for name := range settings {
+		if name != "config" && app.GetFlag(name) != nil {
+			continue
+		}
+		if pc.SelectedCommand != nil && pc.SelectedCommand.GetFlag(name) != nil {
+			continue
+		}
+		if owner := commandOfFlag(app, name); owner != "" && pc.SelectedCommand != nil {
+			return nil, fmt.Errorf("setting [%s] in config file [%s] is a flag of command %s, not of %s, "+
+				"keep it in a profile used with %s", name, path, owner, pc.SelectedCommand.FullCommand(), owner)
+		}
+		return nil, fmt.Errorf("unknown setting [%s] in config file [%s], settings are named after flags", name, path)
+	}
+	fileArgs, err := config.Args(settings, given)
+	if err != nil {
+		return nil, fmt.Errorf("config file [%s]: %v", path, err)
+	}
+	klog.Infof("Apply settings from config file [%s]: %v", path, fileArgs)
+
+	// Flags of a command are only accepted after it, and nothing after -- is a flag.
+	end := len(args)
+	for i, arg := range args {
+		if arg == "--" {
+			end = i
+			break
+		}
+	}
+	withFile := append(append(append([]string{}, args[:end]...), fileArgs...), args[end:]...)
+
+	return withFile, nil
+}
+
+// commandOfFlag returns the command having the flag name, or empty if no command has it.
+func commandOfFlag(app *kingpin.Application, name string) string {
+	for _, cmd := range app.Model().FlattenedCommands() {
+		for _, flag := range cmd.Flags {
+			if flag.Name == name {
+				return cmd.FullCommand
+			}
+		}
+	}
+
+	return ""
+}
+
+func listChecks() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION")
+	for _, c := range check.All() {
+		fmt.Fprintf(w, "%s\t%s\n", c.Name(), c.Description())
+	}
+	w.Flush()
+}
+
+// runInstall renders the CronJob running the checks on a schedule and its RBAC, then prints
+// them if render is set or applies them to the cluster otherwise.
+func runInstall(cfg config.CommandArg, opts install.Options, render bool) int {
+	opts.Namespace = cfg.Namespace
+	opts.IngressNamespace = cfg.IngressNamespace
+	if opts.IngressNamespace == "" {
+		klog.Errorf("--ingress-namespace is required to grant access to the ingress controller")
+		return ExitSetupFailed
+	}
+	// Creating namespaces and binding roles inside them cannot be limited to the namespaces
+	// of the runs with RBAC, the CronJob would be able to grant itself anything anywhere.
+	ephemeral := cfg.EphemeralNamespace
+	for _, arg := range opts.Args {
+		if arg == "--ephemeral-namespace" || arg == "--ephemeral-namespace=true" {
+			ephemeral = true
+		}
+	}
+	if ephemeral {
+		klog.Errorf("--ephemeral-namespace cannot be installed, the CronJob runs in --namespace")
+		return ExitSetupFailed
+	}
+
+	if render {
+		opts.CronJobAPIVersion = install.CronJobV1
+		objects, err := install.Render(opts)
+		if err == nil {
+			err = install.Write(os.Stdout, objects)
+		}
+		if err != nil {
+			klog.Errorf("Error happened when render objects: %s", err.Error())
+			return ExitSetupFailed
+		}
+		return ExitOK
+	}
+
+	checker, client, err := connectInstall(cfg, &opts)
+	if err != nil {
+		klog.Errorf("Cannot connect to cluster: %s", err.Error())
+		return ExitSetupFailed
+	}
 	defer checker.Cancel()
 
-	checker.VerifyFlags()
-	
-        klog.Infoln("Start to verify k8s function.")
-	ingClass := checker.GetDefaultIngressClass()
-	ingAnnotate := checker.GetIngressAnnotationValue()
-	if cfg.Storageclass == "" {
-		cfg.Storageclass = checker.GetDefaultStorageClass()
+	objects, err := install.Render(opts)
+	if err == nil {
+		err = install.Apply(checker.Ctx, client, objects)
+	}
+	if err != nil {
+		klog.Errorf("Error happened when install: %s", err.Error())
+		return ExitSetupFailed
 	}
+	klog.Infof("Installed CronJob [%s/k8s-function-checker] with schedule [%s]", opts.Namespace, opts.Schedule)
 
-	rs := new(resource.Operators)
-	sts := resource.NewStatefulSet(cfg.Namespace, cfg.Storageclass, apiresource.MustParse(cfg.Capacity))
-	rs.Add(resource.NewConfigMap(cfg.Namespace), resource.NewService(cfg.Namespace), sts)
-	if ingClass == "" && ingAnnotate == "" {
-		klog.Warningf("Cannot find either default ingressclass or --ingress-class flag," +
-			"will not create ingress resource.")
+	return ExitOK
+}
+
+func runUninstall(cfg config.CommandArg) int {
+	opts := install.Options{Namespace: cfg.Namespace, IngressNamespace: cfg.IngressNamespace}
+	if opts.IngressNamespace == "" {
+		klog.Errorf("--ingress-namespace is required to find the objects created by install")
+		return ExitSetupFailed
+	}
+
+	checker, client, err := connectInstall(cfg, &opts)
+	if err != nil {
+		klog.Errorf("Cannot connect to cluster: %s", err.Error())
+		return ExitSetupFailed
+	}
+	defer checker.Cancel()
+
+	objects, err := install.Render(opts)
+	if err == nil {
+		err = install.Delete(checker.Ctx, client, objects)
+	}
+	if err != nil {
+		klog.Errorf("Error happened when uninstall: %s", err.Error())
+		return ExitCleanupFailed
+	}
+
+	return ExitOK
+}
+
+// connectInstall connects to the cluster and discovers the CronJob API version it serves.
+func connectInstall(cfg config.CommandArg, opts *install.Options) (*config.Checker, dynamic.Interface, error) {
+	checker, err := config.NewChecker(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := dynamic.NewForConfig(checker.RestConf)
+	if err == nil {
+		opts.CronJobAPIVersion, err = install.CronJobAPIVersion(checker.Client.Discovery())
+	}
+	if err != nil {
+		checker.Cancel()
+		return nil, nil, err
+	}
+
+	return checker, client, nil
+}
+
+// runCleanup shows the objects left behind by runs created more than olderThan ago, or only by
+// the run runID if it is set, and deletes them if yes is set.
+func runCleanup(cfg config.CommandArg, olderThan time.Duration, runID string, yes bool) int {
+	checker, err := config.NewChecker(cfg)
+	if err != nil {
+		klog.Errorf("Cannot connect to cluster: %s", err.Error())
+		return ExitSetupFailed
+	}
+	defer checker.Cancel()
+
+	client, err := metadata.NewForConfig(checker.RestConf)
+	if err != nil {
+		klog.Errorf("Cannot connect to cluster: %s", err.Error())
+		return ExitSetupFailed
+	}
+	cleaner := &cleanup.Cleaner{
+		Client:    client,
+		Selector:  resource.ComponentSelector,
+		OlderThan: olderThan,
+	}
+	if runID != "" {
+		// The objects of a single run are asked for by ID, however recent they are.
+		cleaner.Selector = resource.SelectorForRun(runID)
+		cleaner.OlderThan = 0
+	}
+	objects, err := cleaner.Find(checker.Ctx)
+	if err != nil {
+		klog.Errorf("Error happened when find objects left behind: %s", err.Error())
+		return ExitCleanupFailed
+	}
+	if len(objects) == 0 {
+		klog.Infof("No objects left behind older than %s", cleaner.OlderThan)
+		return ExitOK
+	}
+	if err := cleanup.Print(os.Stdout, objects, time.Now()); err != nil {
+		klog.Warningf("Error happened when print objects: %s", err.Error())
+	}
+	if !yes {
+		klog.Infof("Found %d objects left behind, run again with --yes to delete them", len(objects))
+		return ExitOK
+	}
+
+	if err := cleaner.Delete(checker.Ctx, objects); err != nil {
+		klog.Errorf("Error happened when delete objects left behind: %s", err.Error())
+		return ExitCleanupFailed
+	}
+	klog.Infof("Deleted %d objects left behind", len(objects))
+
+	return ExitOK
+}
+
+// runServe runs checks every interval until a signal is received, the results are served on listen.
+// Only the latest retain diagnostics bundles are left after each run.
+func runServe(cfg config.CommandArg, checks []check.Check, listen string, interval time.Duration, retain int) int {
+	srv := serve.New()
+	httpServer := &http.Server{Addr: listen, Handler: srv.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	klog.Infof("Serve on [%s], run checks every %s", listen, interval)
+
+	// A signal also cancels the run in progress, which cleans up before the loop stops.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	stop := make(chan struct{})
+	code := make(chan int, 1)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			klog.Warningf("Received signal [%s], stop serving", sig)
+			code <- ExitOK
+		case err := <-errCh:
+			klog.Errorf("Cannot serve on [%s]: %s", listen, err.Error())
+			code <- ExitSetupFailed
+		}
+		close(stop)
+	}()
+
+	srv.Loop(interval, stop, func() *report.Report {
+		rp := run(cfg, checks)
+		rp.Finish()
+		logSummary(rp)
+		if cfg.DiagnosticsDir != "" {
+			if err := diagnostics.Prune(cfg.DiagnosticsDir, retain); err != nil {
+				klog.Warningf("Error happened when remove old diagnostics: %s", err.Error())
+			}
+		}
+		return rp
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		klog.Warningf("Error happened when shut down server: %s", err.Error())
+	}
+	klog.Flush()
+
+	return <-code
+}
+
+func logSummary(rp *report.Report) {
+	if rp.Failed() {
+		klog.Infof("Function test with %d errors", rp.Summary.Failed)
 	} else {
-		rs.Add(resource.NewIngress(cfg.Namespace, ingClass, ingAnnotate, cfg.Domain))
+		klog.Infoln("All function test successfully")
 	}
+}
 
-	cleanFunc := func() {
-		if err := rs.Delete(checker.Client); err != nil {
-			klog.Warningf("Error happened when delete resource: %s", err.Error())
+// runChecks runs the selected checks, writes the reports and returns the exit code.
+func runChecks(cfg config.CommandArg, checks []check.Check) int {
+	rp := run(cfg, checks)
+	rp.Finish()
+	logSummary(rp)
+
+	if cfg.Output != "" {
+		if err := rp.Write(os.Stdout, cfg.Output); err != nil {
+			klog.Errorf("Error happened when write report: %s", err.Error())
+		}
+	}
+
+	if cfg.JUnitFile != "" {
+		if err := rp.WriteJUnitFile(cfg.JUnitFile); err != nil {
+			klog.Errorf("Error happened when write junit report: %s", err.Error())
+		}
+	}
+
+	klog.Flush()
+	return exitCode(rp)
+}
+
+// exitCode maps the report to the process exit code. A setup failure wins over
+// check failures, which in turn win over a failed cleanup.
+func exitCode(rp *report.Report) int {
+	switch {
+	case rp.FailedIn(report.PhaseSetup):
+		return ExitSetupFailed
+	case rp.FailedIn(report.PhaseCheck):
+		return ExitCheckFailed
+	case rp.FailedIn(report.PhaseCleanup):
+		return ExitCleanupFailed
+	default:
+		return ExitOK
+	}
+}
+
+func run(cfg config.CommandArg, checks []check.Check) *report.Report {
+	rp := report.New()
+	// Every run gets its own objects, so that it neither collides with nor cleans up
+	// those of another run sharing the namespace.
+	resource.RunID = resource.NewRunID()
+	rp.RunID = resource.RunID
+	klog.Infof("Start run [%s]", resource.RunID)
+
+	// Rendering needs no cluster, it neither connects nor verifies flags against one.
+	if cfg.DryRun == config.DryRunClient {
+		return renderResources(cfg, rp, checks)
+	}
+
+	var checker *config.Checker
+	res := rp.Run(report.PhaseSetup, "connect cluster", func(res *report.Result) (err error) {
+		checker, err = config.NewChecker(cfg)
+		if err != nil {
+			return err
 		}
+		res.AddEvidence("server", checker.RestConf.Host)
+		res.AddEvidence("source", checker.Source)
+		return nil
+	})
+	if res.Status == report.StatusFail {
+		klog.Errorf("Cannot connect to cluster: %s", res.Message)
+		return rp
 	}
+	defer checker.Cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
 	go func() {
 		select {
-		case <-sigCh:
-			cleanFunc()
-			os.Exit(1)
+		case sig := <-sigCh:
+			klog.Warningf("Received signal [%s], cancel running checks and clean up", sig)
+			checker.Cancel()
+		case <-checker.Ctx.Done():
 		}
 	}()
+
+	var ingClass, ingAnnotate string
+	res = rp.Run(report.PhaseSetup, "verify flags", func(*report.Result) error {
+		if err := checker.VerifyFlags(); err != nil {
+			return err
+		}
+
+		klog.Infoln("Start to verify k8s function.")
+		ingClass = checker.GetDefaultIngressClass()
+		ingAnnotate = checker.GetIngressAnnotationValue()
+		if cfg.Storageclass == "" {
+			sc, err := checker.GetDefaultStorageClass()
+			if err != nil {
+				return err
+			}
+			cfg.Storageclass = sc
+		}
+		return nil
+	})
+	if res.Status == report.StatusFail {
+		klog.Errorf("Precondition not satisfied: %s", res.Message)
+		return rp
+	}
+
+	rs := new(resource.Operators)
+	var ns *resource.Namespace
+	if cfg.EphemeralNamespace {
+		ns = resource.NewNamespace(cfg.PodSecurityLevel)
+		cfg.Namespace = ns.Name()
+	}
+	if ns != nil && cfg.DryRun == "" {
+		res = rp.Run(report.PhaseSetup, "create "+ns.FormatedName(), func(*report.Result) error {
+			return ns.Create(checker.Ctx, checker.Client)
+		})
+		if res.Status == report.StatusFail {
+			klog.Warningf("Error happened when create namespace: %s", res.Message)
+			check.Skip(checks, rp, "namespace was not created")
+			return rp
+		}
+		klog.Infof("Resource [%s] create successfully", ns.FormatedName())
+
+		// Deleting the namespace removes every resource of the run, so it replaces the
+		// cleanup of single resources.
+		defer rp.Run(report.PhaseCleanup, "delete "+ns.FormatedName(), func(res *report.Result) error {
+			if keepResources(cfg, rp, res, rs, ns) {
+				return nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout+cfg.NamespaceTimeout)
+			defer cancel()
+
+			err := ns.Delete(ctx, checker.Client)
+			if err == nil {
+				err = ns.WaitForDeleted(ctx, checker.Client, cfg.NamespaceTimeout)
+			}
+			if err != nil {
+				klog.Warningf("Error happened when delete namespace: %s", err.Error())
+			}
+			return err
+		})
+
+		if rb := namespaceRoleBinding(cfg); rb != nil {
+			res = rp.Run(report.PhaseSetup, "create "+rb.FormatedName(), func(*report.Result) error {
+				if err := rb.Create(checker.Ctx, checker.Client); err != nil {
+					return err
+				}
+				return rb.WaitForGranted(checker.Ctx, checker.Client, cfg.ReadyTimeout)
+			})
+			if res.Status == report.StatusFail {
+				klog.Warningf("Error happened when bind role: %s", res.Message)
+				check.Skip(checks, rp, "namespace role was not bound")
+				return rp
+			}
+			rs.Add(rb)
+		}
+	}
+
+	var ing *resource.Ingress
+	if ingClass == "" && ingAnnotate == "" {
+		klog.Warningf("Cannot find either default ingressclass or --ingress-class flag," +
+			"will not create ingress resource.")
+	} else {
+		ing = resource.NewIngress(cfg.Namespace, ingClass, ingAnnotate, cfg.Domain)
+	}
+
+	env, ops, lateOps, err := newResources(cfg, checker, rp, rs, ing, checks)
+	if err != nil {
+		return rp
+	}
+	if cfg.DryRun != "" {
+		dryRun(cfg, checker, rp, append(ops, lateOps...))
+		check.Skip(checks, rp, "dry run")
+		return rp
+	}
+	rs.Add(ops...)
+
+	// The run context may already be cancelled, clean up with a context of its own.
+	if !cfg.EphemeralNamespace {
+		defer rp.Run(report.PhaseCleanup, "cleanup", deleteResources(cfg, checker.Client, rp, rs))
+	}
+	// Deferred after cleanup so that evidence is collected before it is deleted.
 	defer func() {
-		cleanFunc()
+		if rp.Failed() && cfg.DiagnosticsDir != "" {
+			collectDiagnostics(cfg, checker, rp)
+		}
 	}()
 
-	var allErrors []error
-	err := rs.Create(checker.Client)
-	if err != nil {
-		klog.Warningf(err.Error())
-		allErrors = append(allErrors, err)
+	for _, op := range ops {
+		res = rp.Run(report.PhaseSetup, "create "+op.FormatedName(), func(*report.Result) error {
+			return op.Create(checker.Ctx, checker.Client)
+		})
+		if res.Status == report.StatusFail {
+			klog.Warningf("Error happened when create resource: %s", res.Message)
+			check.Skip(checks, rp, "resources were not created")
+			return rp
+		}
+		klog.Infof("Resource [%s] create successfully", op.FormatedName())
 	}
 
-	sts.WaitForReady(checker.Client)
-	klog.Infoln("Start to test the function of service from internal")
+	res = rp.Run(report.PhaseCheck, "statefulset ready", func(*report.Result) error {
+		return env.StatefulSet.WaitForReady(checker.Ctx, checker.Client, cfg.ReadyTimeout)
+	})
+	if res.Status == report.StatusFail {
+		check.Skip(checks, rp, "statefulset is not ready")
+		return rp
+	}
 
-	if sts.AccessFromInternal(checker.Client, checker.RestConf) {
-		klog.Infoln("Access service from internal successfully")
-	} else {
-		klog.Warningln("Access service from internal failed")
-		allErrors = append(allErrors, err)
+	check.Run(checker.Ctx, env, checks, rp)
+
+	return rp
+}
+
+// newResources builds the resources of the run and the environment the checks run in, ing is
+// nil when no ingress is created. The resources which the checks create themselves while they
+// run are returned apart.
+func newResources(cfg config.CommandArg, checker *config.Checker, rp *report.Report, rs *resource.Operators,
+	ing *resource.Ingress, checks []check.Check) (*check.Env, []resource.OperatorInterface, []resource.OperatorInterface, error) {
+	sts := resource.NewStatefulSet(cfg.Namespace, cfg.Storageclass, apiresource.MustParse(cfg.Capacity))
+	svc := resource.NewService(cfg.Namespace)
+	headless := resource.NewHeadlessService(cfg.Namespace)
+	ops := []resource.OperatorInterface{resource.NewConfigMap(cfg.Namespace), svc, headless, sts}
+
+	var secret *resource.TLSSecret
+	if cfg.IngressTLS && ing != nil {
+		res := rp.Run(report.PhaseSetup, "generate certificate", func(*report.Result) (err error) {
+			secret, err = resource.NewTLSSecret(cfg.Namespace, ing.Host())
+			return err
+		})
+		if res.Status == report.StatusFail {
+			klog.Errorf("Cannot generate certificate for ingress: %s", res.Message)
+			return nil, nil, nil, errors.New(res.Message)
+		}
+		ing.EnableTLS(secret)
+		// The secret exists before the ingress refers to it, otherwise the controller may
+		// serve and cache its default certificate.
+		ops = append(ops, secret)
+	}
+	if ing != nil {
+		ops = append(ops, ing)
 	}
 
-	klog.Infof("Waiting for user to access from browser,ingress domain is [%s]."+
-		"Press 'y' if the test was successfully, 'n' if it was not.", cfg.Domain)
-	if WaitForUser() {
-		klog.Infof("Ingress access test successfully")
-	} else {
-		klog.Infof("Ingress access test failed")
-		allErrors = append(allErrors, err)
+	env := &check.Env{
+		Config:      cfg,
+		Checker:     checker,
+		StatefulSet: sts,
+		Service:     svc,
+		Headless:    headless,
+		Ingress:     ing,
+		TLSSecret:   secret,
+		Resources:   rs,
 	}
+	ops = append(ops, check.Provision(env, checks)...)
 
-	if len(allErrors) > 0 {
-		klog.Infoln("Function test with %d errors", len(allErrors))
-	} else {
-		klog.Infoln("All function test successfully")
+	return env, ops, check.LateProvision(env, checks), nil
+}
+
+// renderResources prints the resources of the run for --dry-run=client without connecting to
+// the cluster. The storageclass and ingressclass which are not given are left out, so that the
+// defaults of the cluster the manifests are applied to take over.
+func renderResources(cfg config.CommandArg, rp *report.Report, checks []check.Check) *report.Report {
+	res := rp.Run(report.PhaseSetup, "verify flags", func(*report.Result) error {
+		return config.VerifyCapacity(cfg.Capacity)
+	})
+	if res.Status == report.StatusFail {
+		klog.Errorf("Precondition not satisfied: %s", res.Message)
+		return rp
+	}
+
+	var ns *resource.Namespace
+	if cfg.EphemeralNamespace {
+		ns = resource.NewNamespace(cfg.PodSecurityLevel)
+		cfg.Namespace = ns.Name()
+	}
+	ing := resource.NewIngress(cfg.Namespace, "", "", cfg.Domain)
+	_, ops, lateOps, err := newResources(cfg, nil, rp, new(resource.Operators), ing, checks)
+	if err != nil {
+		return rp
 	}
+	if ns != nil {
+		if rb := namespaceRoleBinding(cfg); rb != nil {
+			ops = append([]resource.OperatorInterface{rb}, ops...)
+		}
+		ops = append([]resource.OperatorInterface{ns}, ops...)
+	}
+	dryRun(cfg, nil, rp, append(ops, lateOps...))
+	check.Skip(checks, rp, "dry run")
+
+	return rp
 }
 
-func WaitForUser() bool {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		input := scanner.Text()
-		if strings.EqualFold(input, "y") {
-			return true
-		} else if strings.EqualFold(input, "n") {
-			return false
-		} else {
-			klog.Info("Invalid input. Please press 'y' if the test was successful, 'n' if it was not:")
+// namespaceRoleBinding returns the binding of --namespace-role inside the namespace of the run,
+// or nil if it is not set.
+func namespaceRoleBinding(cfg config.CommandArg) *resource.RoleBinding {
+	if cfg.NamespaceRole == "" {
+		return nil
+	}
+	sa := strings.SplitN(cfg.NamespaceRoleSA, ":", 2)
+	return resource.NewRoleBinding(cfg.Namespace, cfg.NamespaceRole, sa[0], sa[1])
+}
+
+// deleteResources returns the cleanup of the resources in rs, unless --keep leaves them in place.
+func deleteResources(cfg config.CommandArg, client kubernetes.Interface, rp *report.Report,
+	rs *resource.Operators) func(res *report.Result) error {
+	return func(res *report.Result) error {
+		if keepResources(cfg, rp, res, rs, nil) {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+
+		err := rs.Delete(ctx, client)
+		if err != nil {
+			klog.Warningf("Error happened when delete resource: %s", err.Error())
 		}
+		return err
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		klog.Error(err)
+// keepResources tells whether the resources of the run are left in place as --keep says. If so
+// it records them in rp, and how to inspect and remove them in res and the log.
+func keepResources(cfg config.CommandArg, rp *report.Report, res *report.Result, rs *resource.Operators,
+	ns *resource.Namespace) bool {
+	if cfg.Keep != config.KeepAlways && !(cfg.Keep == config.KeepOnFailure && rp.Failed()) {
 		return false
 	}
 
-	return false
+	var kept []string
+	if ns != nil && ns.IsCreated() {
+		kept = append(kept, ns.FormatedName())
+	}
+	for _, op := range rs.Created() {
+		kept = append(kept, op.FormatedName())
+		// Claims are created by the statefulset controller, they are kept along with it.
+		if sts, ok := op.(*resource.StatefulSet); ok {
+			for _, claim := range sts.ClaimNames() {
+				kept = append(kept, strings.Join([]string{sts.Namespace(), "persistentvolumeclaims", claim}, "/"))
+			}
+		}
+	}
+	rp.Kept = kept
+
+	commands := inspectCommands(cfg)
+	klog.Infof("Keep %d resources of run [%s] with --keep=%s, inspect them with:", len(kept), resource.RunID, cfg.Keep)
+	for _, command := range commands {
+		klog.Infof("  %s", command)
+	}
+	res.AddEvidence("inspect", strings.Join(commands, "\n"))
+	res.Skipf("resources kept with --keep=%s", cfg.Keep)
+
+	return true
+}
+
+// inspectCommands returns the commands showing the resources of the run and removing them.
+func inspectCommands(cfg config.CommandArg) []string {
+	var connect string
+	if cfg.Kubeconfig != "" {
+		connect += " --kubeconfig=" + cfg.Kubeconfig
+	}
+	if cfg.Context != "" {
+		connect += " --context=" + cfg.Context
+	}
+	kubectl := fmt.Sprintf("kubectl%s -n %s", connect, cfg.Namespace)
+	selector := resource.RunIDLabel + "=" + resource.RunID
+
+	return []string{
+		fmt.Sprintf("%s get statefulsets,pods,services,endpoints,ingresses,persistentvolumeclaims,"+
+			"configmaps,secrets,networkpolicies -l %s -o wide", kubectl, selector),
+		fmt.Sprintf("%s describe statefulsets,pods,services,ingresses,persistentvolumeclaims -l %s", kubectl, selector),
+		fmt.Sprintf("%s get events --sort-by=.lastTimestamp", kubectl),
+		fmt.Sprintf("%s logs -l %s --all-containers --prefix", kubectl, selector),
+		fmt.Sprintf("k8s-function-checker%s cleanup --run-id=%s --yes", connect, resource.RunID),
+	}
+}
+
+// dryRun prints ops or submits them with server-side dry run, as cfg.DryRun says. checker is
+// only used by server-side dry run.
+func dryRun(cfg config.CommandArg, checker *config.Checker, rp *report.Report, ops []resource.OperatorInterface) {
+	if cfg.DryRun == config.DryRunClient {
+		res := rp.Run(report.PhaseSetup, "render resources", func(*report.Result) error {
+			return resource.WriteManifests(os.Stdout, ops)
+		})
+		if res.Status == report.StatusFail {
+			klog.Errorf("Error happened when render resources: %s", res.Message)
+		}
+		return
+	}
+
+	resource.DryRun = []string{metav1.DryRunAll}
+	defer func() { resource.DryRun = nil }()
+	// Every resource is submitted even if one is rejected, so that a review sees all rejections.
+	for _, op := range ops {
+		res := rp.Run(report.PhaseSetup, "dry run create "+op.FormatedName(), func(*report.Result) error {
+			return op.Create(checker.Ctx, checker.Client)
+		})
+		if res.Status == report.StatusFail {
+			klog.Warningf("Resource [%s] rejected: %s", op.FormatedName(), res.Message)
+			continue
+		}
+		klog.Infof("Resource [%s] accepted by server-side dry run", op.FormatedName())
+	}
+}
+
+func collectDiagnostics(cfg config.CommandArg, checker *config.Checker, rp *report.Report) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	collector := &diagnostics.Collector{
+		Client:    checker.Client,
+		Namespace: cfg.Namespace,
+		Selector:  resource.RunSelector(),
+		Container: resource.ContainerName,
+	}
+	path, err := collector.Collect(ctx, cfg.DiagnosticsDir, cfg.DiagnosticsTar)
+	if err != nil {
+		klog.Warningf("Error happened when collect diagnostics: %s", err.Error())
+	}
+	if path != "" {
+		klog.Infof("Diagnostics collected into [%s]", path)
+		rp.Diagnostics = path
+	}
 }
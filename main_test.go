@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/tiggoins/function-checker/check"
+	"github.com/tiggoins/function-checker/config"
+	"github.com/tiggoins/function-checker/report"
+	"github.com/tiggoins/function-checker/resource"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace = "function-check"
+	testRunID     = "abc123"
+)
+
+func init() {
+	resource.RunID = testRunID
+}
+
+func TestExitCode(t *testing.T) {
+	result := func(phase report.Phase, status report.Status) report.Result {
+		return report.Result{Name: string(phase) + "-" + string(status), Phase: phase, Status: status}
+	}
+
+	tests := []struct {
+		name    string
+		results []report.Result
+		want    int
+	}{
+		{
+			name: "pass",
+			results: []report.Result{
+				result(report.PhaseSetup, report.StatusPass),
+				result(report.PhaseCheck, report.StatusPass),
+				result(report.PhaseCleanup, report.StatusPass),
+			},
+			want: ExitOK,
+		},
+		{
+			name: "check failed",
+			results: []report.Result{
+				result(report.PhaseSetup, report.StatusPass),
+				result(report.PhaseCheck, report.StatusFail),
+				result(report.PhaseCleanup, report.StatusPass),
+			},
+			want: ExitCheckFailed,
+		},
+		{
+			name: "setup failed",
+			results: []report.Result{
+				result(report.PhaseSetup, report.StatusFail),
+				result(report.PhaseCheck, report.StatusSkip),
+			},
+			want: ExitSetupFailed,
+		},
+		{
+			name: "cleanup failed",
+			results: []report.Result{
+				result(report.PhaseCheck, report.StatusPass),
+				result(report.PhaseCleanup, report.StatusFail),
+			},
+			want: ExitCleanupFailed,
+		},
+		{
+			name: "skip only",
+			results: []report.Result{
+				result(report.PhaseCheck, report.StatusSkip),
+				result(report.PhaseCheck, report.StatusSkip),
+			},
+			want: ExitOK,
+		},
+		{
+			name: "mixed with check and cleanup failed",
+			results: []report.Result{
+				result(report.PhaseSetup, report.StatusPass),
+				result(report.PhaseCheck, report.StatusPass),
+				result(report.PhaseCheck, report.StatusSkip),
+				result(report.PhaseCheck, report.StatusFail),
+				result(report.PhaseCleanup, report.StatusFail),
+			},
+			want: ExitCheckFailed,
+		},
+		{
+			name: "mixed with setup failed",
+			results: []report.Result{
+				result(report.PhaseSetup, report.StatusPass),
+				result(report.PhaseSetup, report.StatusFail),
+				result(report.PhaseCheck, report.StatusFail),
+				result(report.PhaseCleanup, report.StatusFail),
+			},
+			want: ExitSetupFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := report.New()
+			for _, res := range tt.results {
+				rp.Add(res)
+			}
+			if got := exitCode(rp); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectCommands(t *testing.T) {
+	cfg := config.CommandArg{Namespace: testNamespace, Kubeconfig: "/tmp/kubeconfig", Context: "test"}
+	commands := inspectCommands(cfg)
+	if len(commands) != 5 {
+		t.Fatalf("inspectCommands() = %v, want 5 commands", commands)
+	}
+	for _, command := range commands {
+		if !strings.Contains(command, " --kubeconfig=/tmp/kubeconfig --context=test ") {
+			t.Errorf("command %q does not connect to the cluster of the run", command)
+		}
+	}
+
+	// resources returns the resources listed after verb in command.
+	resources := func(command, verb string) map[string]bool {
+		fields := strings.Fields(command)
+		for i, field := range fields {
+			if field == verb && i+1 < len(fields) {
+				listed := map[string]bool{}
+				for _, r := range strings.Split(fields[i+1], ",") {
+					listed[r] = true
+				}
+				return listed
+			}
+		}
+		return nil
+	}
+	got, described := resources(commands[0], "get"), resources(commands[1], "describe")
+
+	tests := []struct {
+		resource string
+		describe bool
+	}{
+		{resource: "statefulsets", describe: true},
+		{resource: "pods", describe: true},
+		{resource: "services", describe: true},
+		{resource: "endpoints"},
+		{resource: "ingresses", describe: true},
+		{resource: "persistentvolumeclaims", describe: true},
+		{resource: "configmaps"},
+		{resource: "secrets"},
+		{resource: "networkpolicies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			if !got[tt.resource] {
+				t.Errorf("%q does not get %s", commands[0], tt.resource)
+			}
+			if described[tt.resource] != tt.describe {
+				t.Errorf("%q describes %s = %t, want %t", commands[1], tt.resource, described[tt.resource], tt.describe)
+			}
+		})
+	}
+
+	selector := "-l " + resource.RunIDLabel + "=" + testRunID
+	for _, command := range []string{commands[0], commands[1], commands[3]} {
+		if !strings.Contains(command, selector) {
+			t.Errorf("command %q does not select the run with %q", command, selector)
+		}
+	}
+	if want := "k8s-function-checker --kubeconfig=/tmp/kubeconfig --context=test cleanup --run-id=" + testRunID + " --yes"; commands[4] != want {
+		t.Errorf("cleanup command = %q, want %q", commands[4], want)
+	}
+}
+
+func TestDeleteResourcesKeep(t *testing.T) {
+	tests := []struct {
+		keep     string
+		failed   bool
+		wantKept bool
+	}{
+		{keep: config.KeepNever, failed: true},
+		{keep: config.KeepOnFailure},
+		{keep: config.KeepOnFailure, failed: true, wantKept: true},
+		{keep: config.KeepAlways, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		name := tt.keep
+		if tt.failed {
+			name += " failed"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			client := fake.NewSimpleClientset()
+			cm := resource.NewConfigMap(testNamespace)
+			sts := resource.NewStatefulSet(testNamespace, "standard", apiresource.MustParse("1Gi"))
+			rs := new(resource.Operators)
+			rs.Add(cm, sts)
+			if err := rs.Create(ctx, client); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			rp := report.New()
+			if tt.failed {
+				rp.Add(report.Result{Name: "dns", Phase: report.PhaseCheck, Status: report.StatusFail})
+			}
+			cfg := config.CommandArg{Namespace: testNamespace, Keep: tt.keep}
+			res := rp.Run(report.PhaseCleanup, "cleanup", deleteResources(cfg, client, rp, rs))
+
+			cms, err := client.CoreV1().ConfigMaps(testNamespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if kept := len(cms.Items) == 1; kept != tt.wantKept {
+				t.Errorf("configmap kept = %t, want %t", kept, tt.wantKept)
+			}
+
+			if !tt.wantKept {
+				if res.Status != report.StatusPass || len(rp.Kept) != 0 {
+					t.Errorf("cleanup = %s with kept %v, want pass with nothing kept", res.Status, rp.Kept)
+				}
+				return
+			}
+			if res.Status != report.StatusSkip || res.Evidence["inspect"] == "" {
+				t.Errorf("cleanup = %s with evidence %v, want skip with inspect commands", res.Status, res.Evidence)
+			}
+			// The configmap, the statefulset and a claim per replica.
+			if want := 2 + len(sts.ClaimNames()); len(rp.Kept) != want || rp.Kept[0] != cm.FormatedName() {
+				t.Errorf("kept %v, want %d resources starting with %s", rp.Kept, want, cm.FormatedName())
+			}
+		})
+	}
+}
+
+func TestRunClientDryRunWithoutCluster(t *testing.T) {
+	defer func(id string) { resource.RunID = id }(resource.RunID)
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	cfg := config.CommandArg{
+		Kubeconfig:         "/nonexistent/kubeconfig",
+		Domain:             "nginx-test.example.com",
+		Capacity:           "1Gi",
+		IngressTLS:         true,
+		EphemeralNamespace: true,
+		DryRun:             config.DryRunClient,
+	}
+	rendered := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		rendered <- string(out)
+	}()
+	rp := run(cfg, check.All())
+	w.Close()
+	out := <-rendered
+
+	if rp.Failed() {
+		t.Fatalf("run() failed: %+v", rp.Results)
+	}
+	for _, res := range rp.Results {
+		if res.Name == "connect cluster" {
+			t.Errorf("client dry run connected to the cluster")
+		}
+	}
+	for _, want := range []string{"kind: Namespace", "kind: StatefulSet", "kind: Secret", "kind: Ingress",
+		"host: " + resource.RunHost(cfg.Domain)} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered resources do not contain %q", want)
+		}
+	}
+}
+
+func TestWithConfigFileCommandFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "function-checker.yaml")
+	data := "namespace: function-check\nschedule: \"0 * * * *\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	newApp := func() *kingpin.Application {
+		app := kingpin.New("k8s-function-checker", "")
+		app.Flag("config", "").String()
+		app.Flag("profile", "").String()
+		app.Flag("namespace", "").String()
+		app.Command("run", "").Default()
+		install := app.Command("install", "")
+		install.Flag("schedule", "").String()
+		install.Arg("run-args", "").Strings()
+		return app
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "command flags after the command",
+			args: []string{"--config", path, "install", "--", "--checks=dns"},
+			want: []string{"--config", path, "install", "--namespace=function-check", "--schedule=0 * * * *", "--", "--checks=dns"},
+		},
+		{
+			name: "given flags win",
+			args: []string{"--config", path, "install", "--schedule=@daily"},
+			want: []string{"--config", path, "install", "--schedule=@daily", "--namespace=function-check"},
+		},
+		{
+			name:    "flags of another command",
+			args:    []string{"--config", path, "run"},
+			wantErr: "setting [schedule] in config file [" + path + "] is a flag of command install, not of run",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp()
+			got, err := withConfigFile(app, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("withConfigFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("withConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withConfigFile() = %q, want %q", got, tt.want)
+			}
+			if _, err := app.Parse(got); err != nil {
+				t.Errorf("Parse() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const junitSuiteName = "k8s-function-checker"
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit encodes the report to w as a JUnit XML document, one testcase per result.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      junitSuiteName,
+		Tests:     r.Summary.Total,
+		Failures:  r.Summary.Failed,
+		Skipped:   r.Summary.Skipped,
+		Time:      junitSeconds(r.Duration.Duration),
+		Timestamp: r.StartTime.UTC().Format(time.RFC3339),
+	}
+
+	for _, res := range r.Results {
+		tc := junitTestCase{
+			Name:      res.Name,
+			Classname: junitSuiteName + "." + string(res.Phase),
+			Time:      junitSeconds(res.Duration.Duration),
+			SystemOut: formatEvidence(res.Evidence),
+		}
+		switch res.Status {
+		case StatusFail:
+			tc.Failure = &junitMessage{Message: res.Message, Content: res.Message}
+		case StatusSkip:
+			tc.Skipped = &junitMessage{Message: res.Message}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJUnitFile writes the JUnit XML document to path.
+func (r *Report) WriteJUnitFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.WriteJUnit(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatEvidence(evidence map[string]string) string {
+	if len(evidence) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(evidence))
+	for k := range evidence {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s:\n%s\n", k, evidence[k])
+	}
+	return b.String()
+}
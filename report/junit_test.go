@@ -0,0 +1,46 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestReportWriteJUnit(t *testing.T) {
+	rp := newTestReport()
+
+	var buf bytes.Buffer
+	if err := rp.WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("cannot decode junit report: %v\n%s", err, buf.String())
+	}
+	if len(got.Suites) != 1 {
+		t.Fatalf("len(Suites) = %d, want 1", len(got.Suites))
+	}
+
+	suite := got.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("suite tests=%d failures=%d skipped=%d, want 3/1/1", suite.Tests, suite.Failures, suite.Skipped)
+	}
+	if len(suite.TestCases) != 3 {
+		t.Fatalf("len(TestCases) = %d, want 3", len(suite.TestCases))
+	}
+
+	pass, fail, skip := suite.TestCases[0], suite.TestCases[1], suite.TestCases[2]
+	if pass.Failure != nil || pass.Skipped != nil {
+		t.Errorf("passed testcase has failure or skipped element: %+v", pass)
+	}
+	if pass.SystemOut != "output:\nit works!\n" {
+		t.Errorf("passed testcase system-out = %q", pass.SystemOut)
+	}
+	if fail.Failure == nil || fail.Failure.Message != "connection refused" {
+		t.Errorf("failed testcase failure = %+v, want message %q", fail.Failure, "connection refused")
+	}
+	if skip.Skipped == nil || skip.Skipped.Message != "ingress resource was not created" {
+		t.Errorf("skipped testcase skipped = %+v", skip.Skipped)
+	}
+}
@@ -0,0 +1,163 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Phase tells which stage of a run a result belongs to.
+type Phase string
+
+const (
+	PhaseSetup   Phase = "setup"
+	PhaseCheck   Phase = "check"
+	PhaseCleanup Phase = "cleanup"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name     string            `json:"name"`
+	Phase    Phase             `json:"phase"`
+	Status   Status            `json:"status"`
+	Duration metav1.Duration   `json:"duration"`
+	Message  string            `json:"message,omitempty"`
+	Evidence map[string]string `json:"evidence,omitempty"`
+}
+
+// AddEvidence records output captured while running the check.
+func (r *Result) AddEvidence(key, value string) {
+	if r.Evidence == nil {
+		r.Evidence = make(map[string]string)
+	}
+	r.Evidence[key] = value
+}
+
+// Skipf marks the check as skipped, the error returned along with it is ignored.
+func (r *Result) Skipf(format string, args ...interface{}) {
+	r.Status = StatusSkip
+	r.Message = fmt.Sprintf(format, args...)
+}
+
+type Summary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Report aggregates the results of all checks in one run.
+type Report struct {
+	StartTime metav1.Time     `json:"startTime"`
+	Duration  metav1.Duration `json:"duration"`
+	// RunID is suffixed to the name and stamped as label of every object created by the run.
+	RunID   string   `json:"runID,omitempty"`
+	Summary Summary  `json:"summary"`
+	Results []Result `json:"results"`
+	// Diagnostics is the path of the diagnostics bundle collected for a failed run.
+	Diagnostics string `json:"diagnostics,omitempty"`
+	// Kept lists the resources left in place with --keep, they are removed by the cleanup command.
+	Kept []string `json:"kept,omitempty"`
+}
+
+func New() *Report {
+	return &Report{
+		StartTime: metav1.Now(),
+		Results:   []Result{},
+	}
+}
+
+// Run times f and records its outcome as a result named name in the given phase.
+// f reports failure by returning an error and may skip itself through Result.Skipf.
+func (r *Report) Run(phase Phase, name string, f func(res *Result) error) Result {
+	res := Result{Name: name, Phase: phase}
+	start := time.Now()
+	err := f(&res)
+	res.Duration = metav1.Duration{Duration: time.Since(start)}
+
+	switch {
+	case res.Status == StatusSkip:
+	case err != nil:
+		res.Status = StatusFail
+		res.Message = err.Error()
+	default:
+		res.Status = StatusPass
+	}
+
+	r.Add(res)
+	return res
+}
+
+func (r *Report) Add(res Result) {
+	r.Results = append(r.Results, res)
+
+	r.Summary.Total++
+	switch res.Status {
+	case StatusPass:
+		r.Summary.Passed++
+	case StatusFail:
+		r.Summary.Failed++
+	case StatusSkip:
+		r.Summary.Skipped++
+	}
+}
+
+// Finish stamps the total duration of the run.
+func (r *Report) Finish() {
+	r.Duration = metav1.Duration{Duration: time.Since(r.StartTime.Time)}
+}
+
+func (r *Report) Failed() bool {
+	return r.Summary.Failed > 0
+}
+
+// FailedIn reports whether any result of the given phase failed.
+func (r *Report) FailedIn(phase Phase) bool {
+	for _, res := range r.Results {
+		if res.Phase == phase && res.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Write encodes the report to w in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch format {
+	case FormatJSON:
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	case FormatYAML:
+		data, err = yaml.Marshal(r)
+	default:
+		return fmt.Errorf("unsupported output format [%s]", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
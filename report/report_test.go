@@ -0,0 +1,101 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func newTestReport() *Report {
+	rp := New()
+	rp.Run(PhaseSetup, "pass", func(res *Result) error {
+		res.AddEvidence("output", "it works!")
+		return nil
+	})
+	rp.Run(PhaseCheck, "fail", func(*Result) error {
+		return errors.New("connection refused")
+	})
+	rp.Run(PhaseCheck, "skip", func(res *Result) error {
+		res.Skipf("ingress resource was not created")
+		return errors.New("ignored")
+	})
+	rp.Finish()
+	return rp
+}
+
+func TestReportRun(t *testing.T) {
+	rp := newTestReport()
+
+	want := Summary{Total: 3, Passed: 1, Failed: 1, Skipped: 1}
+	if rp.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", rp.Summary, want)
+	}
+	if !rp.Failed() {
+		t.Errorf("Failed() = false, want true")
+	}
+	if rp.FailedIn(PhaseSetup) || !rp.FailedIn(PhaseCheck) || rp.FailedIn(PhaseCleanup) {
+		t.Errorf("FailedIn() should only report the check phase")
+	}
+
+	tests := []struct {
+		status  Status
+		message string
+	}{
+		{StatusPass, ""},
+		{StatusFail, "connection refused"},
+		{StatusSkip, "ingress resource was not created"},
+	}
+	for i, tt := range tests {
+		res := rp.Results[i]
+		if res.Status != tt.status || res.Message != tt.message {
+			t.Errorf("%s: got status=%s message=%q, want status=%s message=%q",
+				res.Name, res.Status, res.Message, tt.status, tt.message)
+		}
+	}
+	if got := rp.Results[0].Evidence["output"]; got != "it works!" {
+		t.Errorf("Evidence[output] = %q, want %q", got, "it works!")
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	rp := newTestReport()
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := rp.Write(&buf, format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			var got Report
+			var err error
+			if format == FormatJSON {
+				err = json.Unmarshal(buf.Bytes(), &got)
+			} else {
+				err = yaml.Unmarshal(buf.Bytes(), &got)
+			}
+			if err != nil {
+				t.Fatalf("cannot decode report: %v\n%s", err, buf.String())
+			}
+
+			if got.Summary != rp.Summary {
+				t.Errorf("Summary = %+v, want %+v", got.Summary, rp.Summary)
+			}
+			if len(got.Results) != len(rp.Results) {
+				t.Fatalf("len(Results) = %d, want %d", len(got.Results), len(rp.Results))
+			}
+			for i := range got.Results {
+				if got.Results[i].Name != rp.Results[i].Name || got.Results[i].Status != rp.Results[i].Status {
+					t.Errorf("Results[%d] = %+v, want %+v", i, got.Results[i], rp.Results[i])
+				}
+			}
+		})
+	}
+
+	if err := rp.Write(&bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("Write() with unsupported format error = nil")
+	}
+}
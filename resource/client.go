@@ -0,0 +1,142 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/klog/v2"
+)
+
+var (
+	clientLabels = map[string]string{
+		"kind":      "client",
+		"component": "k8s-function-checker",
+	}
+)
+
+var _ OperatorInterface = &ClientPod{}
+
+// ClientPod is a standalone pod sending requests to the statefulset, it is selected by
+// network policies through its own labels.
+type ClientPod struct {
+	pod     *corev1.Pod
+	created bool
+}
+
+func NewClientPod(namespace string) *ClientPod {
+	c := new(ClientPod)
+
+	c.pod = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker-client"),
+			Namespace: namespace,
+			Labels:    runLabels(clientLabels),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  ProbeContainerName,
+				Image: Image,
+			}},
+		},
+	}
+
+	return c
+}
+
+func (c *ClientPod) FormatedName() string {
+	return strings.Join([]string{c.pod.Namespace, "pods", c.pod.Name}, "/")
+}
+
+func (c *ClientPod) Object() runtime.Object {
+	return c.pod
+}
+
+func (c *ClientPod) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.CoreV1().Pods(c.pod.Namespace).Create(ctx, c.pod, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	c.created = true
+
+	return nil
+}
+
+func (c *ClientPod) IsCreated() bool {
+	return c.created
+}
+
+func (c *ClientPod) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().Pods(c.pod.Namespace).Delete(ctx, c.pod.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// WaitForReady waits until the pod is ready, on timeout the error explains why it is not.
+func (c *ClientPod) WaitForReady(ctx context.Context, client kubernetes.Interface, timeout time.Duration) error {
+	klog.Infof("Waiting for [%s] to become ready", c.FormatedName())
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", c.pod.Name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(c.pod.Namespace).List(waitCtx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(c.pod.Namespace).Watch(waitCtx, options)
+		},
+	}
+
+	var last *corev1.Pod
+	_, err := watchtools.UntilWithSync(waitCtx, lw, &corev1.Pod{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("[%s] was deleted", c.FormatedName())
+		}
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok {
+			return false, nil
+		}
+		last = pod
+		return IsPodReady(pod), nil
+	})
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != wait.ErrWaitTimeout {
+		return err
+	}
+	if last == nil {
+		return fmt.Errorf("[%s] not ready after %s: not created", c.FormatedName(), timeout)
+	}
+	return fmt.Errorf("[%s] not ready after %s: %s", c.FormatedName(), timeout,
+		strings.Join(podNotReadyReasons(ctx, client, last), "; "))
+}
+
+// Exec runs command in the container of the pod.
+func (c *ClientPod) Exec(ctx context.Context, client kubernetes.Interface, rc *rest.Config,
+	command []string) (string, string, error) {
+	return Exec(ctx, client, rc, c.pod.Namespace, c.pod.Name, ProbeContainerName, command)
+}
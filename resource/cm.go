@@ -6,12 +6,20 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
+const (
+	// configMapName is the name of the configmap before the RunID is suffixed.
+	configMapName = "k8s-function-checker-cm"
+)
+
 var (
-	page   = "it works!"
+	// Page is served by the statefulset and expected by every check requesting it. It is
+	// bound to a command line flag and must be set before any resource is built.
+	Page   = "it works!"
 	script = `#!/bin/bash
       times=$1
       service=$2
@@ -35,12 +43,12 @@ func NewConfigMap(namespace string) *ConfigMap {
 	config := new(ConfigMap)
 	config.cm = &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "k8s-function-checker-cm",
+			Name:      objectName(configMapName),
 			Namespace: namespace,
-			Labels:    cmLabels,
+			Labels:    runLabels(cmLabels),
 		},
 		Data: map[string]string{
-			"index.html":         page,
+			"index.html":         Page,
 			"service-checker.sh": script,
 		},
 	}
@@ -52,8 +60,12 @@ func (c *ConfigMap) FormatedName() string {
 	return strings.Join([]string{c.cm.Namespace, "configmaps", c.cm.Name}, "/")
 }
 
-func (c *ConfigMap) Create(client *kubernetes.Clientset) error {
-	_, err := client.CoreV1().ConfigMaps(c.cm.Namespace).Create(context.Background(), c.cm, metav1.CreateOptions{})
+func (c *ConfigMap) Object() runtime.Object {
+	return c.cm
+}
+
+func (c *ConfigMap) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.CoreV1().ConfigMaps(c.cm.Namespace).Create(ctx, c.cm, metav1.CreateOptions{DryRun: DryRun})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -67,8 +79,8 @@ func (c *ConfigMap) IsCreated() bool {
 	return c.created
 }
 
-func (c *ConfigMap) Delete(client *kubernetes.Clientset) error {
-	err := client.CoreV1().ConfigMaps(c.cm.Namespace).Delete(context.Background(), c.cm.Name, metav1.DeleteOptions{})
+func (c *ConfigMap) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().ConfigMaps(c.cm.Namespace).Delete(ctx, c.cm.Name, metav1.DeleteOptions{})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
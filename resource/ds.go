@@ -0,0 +1,221 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ProbeContainerName is the name of the container in daemonset pods.
+	ProbeContainerName = "function-check-probe"
+)
+
+var (
+	dsLabels = map[string]string{
+		"kind":      "daemonset",
+		"component": "k8s-function-checker",
+	}
+	controlPlaneTaints = []string{
+		"node-role.kubernetes.io/master",
+		"node-role.kubernetes.io/control-plane",
+	}
+)
+
+var _ OperatorInterface = &DaemonSet{}
+
+type DaemonSet struct {
+	ds      *appsv1.DaemonSet
+	created bool
+}
+
+// NewDaemonSet builds a daemonset running a probe pod on every schedulable node,
+// including control-plane nodes if tolerateControlPlane is set.
+func NewDaemonSet(namespace string, tolerateControlPlane bool) *DaemonSet {
+	d := new(DaemonSet)
+	podLabels := runLabels(dsLabels)
+
+	d.ds = &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker-ds"),
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: podLabels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  ProbeContainerName,
+						Image: Image,
+					}},
+				},
+			},
+		},
+	}
+
+	if tolerateControlPlane {
+		for _, key := range controlPlaneTaints {
+			d.ds.Spec.Template.Spec.Tolerations = append(d.ds.Spec.Template.Spec.Tolerations, corev1.Toleration{
+				Key:      key,
+				Operator: corev1.TolerationOpExists,
+				Effect:   corev1.TaintEffectNoSchedule,
+			})
+		}
+	}
+
+	return d
+}
+
+func (d *DaemonSet) FormatedName() string {
+	return strings.Join([]string{d.ds.Namespace, "daemonsets", d.ds.Name}, "/")
+}
+
+func (d *DaemonSet) Object() runtime.Object {
+	return d.ds
+}
+
+func (d *DaemonSet) Namespace() string {
+	return d.ds.Namespace
+}
+
+func (d *DaemonSet) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.AppsV1().DaemonSets(d.ds.Namespace).Create(ctx, d.ds, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	d.created = true
+
+	return nil
+}
+
+func (d *DaemonSet) IsCreated() bool {
+	return d.created
+}
+
+func (d *DaemonSet) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.AppsV1().DaemonSets(d.ds.Namespace).Delete(ctx, d.ds.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Schedulable reports whether the daemonset is expected to run a pod on node.
+func (d *DaemonSet) Schedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range d.ds.Spec.Template.Spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WaitForPods waits until a ready pod runs on each of nodes and returns them by node name.
+// On timeout the returned error explains for each node why its pod is missing or not ready.
+func (d *DaemonSet) WaitForPods(ctx context.Context, client kubernetes.Interface, nodes []string,
+	timeout time.Duration) (map[string]*corev1.Pod, error) {
+	klog.Infof("Waiting for pods of [%s] to become ready on %d nodes", d.FormatedName(), len(nodes))
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	selector := labels.FormatLabels(d.ds.Spec.Selector.MatchLabels)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector
+			return client.CoreV1().Pods(d.ds.Namespace).List(waitCtx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector
+			return client.CoreV1().Pods(d.ds.Namespace).Watch(waitCtx, options)
+		},
+	}
+
+	byNode := map[string]*corev1.Pod{}
+	_, err := watchtools.UntilWithSync(waitCtx, lw, &corev1.Pod{}, nil, func(event watch.Event) (bool, error) {
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok || pod.Spec.NodeName == "" {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			delete(byNode, pod.Spec.NodeName)
+		} else {
+			byNode[pod.Spec.NodeName] = pod
+		}
+
+		for _, node := range nodes {
+			if pod, ok := byNode[node]; !ok || !IsPodReady(pod) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err == nil {
+		return byNode, nil
+	}
+	if ctx.Err() != nil {
+		return byNode, ctx.Err()
+	}
+	if err != wait.ErrWaitTimeout {
+		return byNode, err
+	}
+
+	var notReady []string
+	for _, node := range nodes {
+		pod, ok := byNode[node]
+		if !ok {
+			notReady = append(notReady, fmt.Sprintf("node [%s]: no pod scheduled", node))
+			continue
+		}
+		if !IsPodReady(pod) {
+			notReady = append(notReady, fmt.Sprintf("node [%s]: pod [%s]: %s", node, pod.Name,
+				strings.Join(podNotReadyReasons(ctx, client, pod), "; ")))
+		}
+	}
+	return byNode, fmt.Errorf("[%s] not ready after %s: %s", d.FormatedName(), timeout, strings.Join(notReady, ", "))
+}
+
+// Exec runs command in the probe container of pod podName.
+func (d *DaemonSet) Exec(ctx context.Context, client kubernetes.Interface, rc *rest.Config,
+	podName string, command []string) (string, string, error) {
+	return Exec(ctx, client, rc, d.ds.Namespace, podName, ProbeContainerName, command)
+}
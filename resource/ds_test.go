@@ -0,0 +1,86 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDaemonSetSchedulable(t *testing.T) {
+	node := func(unschedulable bool, taints ...corev1.Taint) *corev1.Node {
+		return &corev1.Node{Spec: corev1.NodeSpec{Unschedulable: unschedulable, Taints: taints}}
+	}
+	controlPlane := corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}
+	preferNo := corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectPreferNoSchedule}
+	dedicated := corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectNoExecute}
+
+	tests := []struct {
+		name                 string
+		node                 *corev1.Node
+		tolerateControlPlane bool
+		want                 bool
+	}{
+		{name: "worker", node: node(false), want: true},
+		{name: "cordoned", node: node(true), tolerateControlPlane: true},
+		{name: "control-plane", node: node(false, controlPlane)},
+		{name: "control-plane tolerated", node: node(false, controlPlane), tolerateControlPlane: true, want: true},
+		{name: "prefer no schedule", node: node(false, preferNo), want: true},
+		{name: "no execute", node: node(false, dedicated), tolerateControlPlane: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDaemonSet(testNamespace, tt.tolerateControlPlane)
+			if got := d.Schedulable(tt.node); got != tt.want {
+				t.Errorf("Schedulable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonSetWaitForPods(t *testing.T) {
+	d := NewDaemonSet(testNamespace, false)
+	pod := func(name, node string, ready bool) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: runLabels(dsLabels)},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+		if ready {
+			p.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		} else {
+			p.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  ProbeContainerName,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}}
+		}
+		return p
+	}
+
+	client := fake.NewSimpleClientset(pod("ds-a", "node-a", true), pod("ds-b", "node-b", true))
+	pods, err := d.WaitForPods(context.Background(), client, []string{"node-a", "node-b"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForPods() error = %v", err)
+	}
+	if pods["node-a"].Name != "ds-a" || pods["node-b"].Name != "ds-b" {
+		t.Errorf("WaitForPods() = %v", pods)
+	}
+
+	client = fake.NewSimpleClientset(pod("ds-a", "node-a", true), pod("ds-b", "node-b", false))
+	_, err = d.WaitForPods(context.Background(), client, []string{"node-a", "node-b", "node-c"}, 100*time.Millisecond)
+	if err == nil {
+		t.Fatalf("WaitForPods() error = nil, want timeout")
+	}
+	for _, want := range []string{
+		"node [node-b]: pod [ds-b]: image pull of container [function-check-probe]: ImagePullBackOff",
+		"node [node-c]: no pod scheduled",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("WaitForPods() error = %q, want it to contain %q", err.Error(), want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+// Exec runs command in container of the pod and returns its stdout and stderr.
+func Exec(ctx context.Context, client kubernetes.Interface, rc *rest.Config,
+	namespace, podName, container string, command []string) (string, string, error) {
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		MaxRetries(3).
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     false,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       false,
+		}, scheme.ParameterCodec)
+
+	klog.V(5).Infof("req.URL()=%s", req.URL().String())
+	exec, err := remotecommand.NewSPDYExecutor(rc, "POST", req.URL())
+	if err != nil {
+		return "", "", err
+	}
+
+	stdout, stderr, err := streamWithContext(ctx, exec)
+	if err != nil {
+		klog.Infof("Error occur when execute command in the pod,err=%s", err.Error())
+		klog.Infof("Exec stdout=%v", stdout)
+		klog.Infof("Exec stderr=%v", stderr)
+		return stdout, stderr, fmt.Errorf("exec [%s] in pod [%s]: %v",
+			strings.Join(command, " "), podName, err)
+	}
+
+	return stdout, stderr, nil
+}
+
+// streamWithContext runs the exec stream and gives up when ctx is done, the executor of this
+// client-go version cannot be cancelled by itself. The stream keeps writing until the server
+// ends it, so it writes into buffers of its own and only what was written so far is returned.
+func streamWithContext(ctx context.Context, exec remotecommand.Executor) (string, string, error) {
+	stdout, stderr := new(syncBuffer), new(syncBuffer)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- exec.Stream(remotecommand.StreamOptions{
+			Stdout: stdout,
+			Stderr: stderr,
+			Tty:    false,
+		})
+	}()
+
+	select {
+	case <-ctx.Done():
+		return stdout.String(), stderr.String(), ctx.Err()
+	case err := <-errCh:
+		return stdout.String(), stderr.String(), err
+	}
+}
+
+// syncBuffer is a bytes.Buffer which can be read while the stream is still writing to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
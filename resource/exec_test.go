@@ -0,0 +1,43 @@
+package resource
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// writingExecutor keeps writing to stdout until done is closed, as a stream the server has not
+// ended yet.
+type writingExecutor struct {
+	done chan struct{}
+}
+
+func (e *writingExecutor) Stream(opts remotecommand.StreamOptions) error {
+	for {
+		select {
+		case <-e.done:
+			return nil
+		default:
+			io.WriteString(opts.Stdout, "x")
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestStreamWithContextCancelled(t *testing.T) {
+	exec := &writingExecutor{done: make(chan struct{})}
+	defer close(exec.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	stdout, _, err := streamWithContext(ctx, exec)
+	if err != context.DeadlineExceeded {
+		t.Errorf("streamWithContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if stdout == "" {
+		t.Errorf("streamWithContext() stdout is empty, want what was written before the deadline")
+	}
+}
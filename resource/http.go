@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	httpProbeTimeout = time.Duration(10) * time.Second
+	httpProbeTimes   = 3
+)
+
+// accessPage sends requests to url with httpClient, with host as Host header if it is not empty,
+// expects the page served by the statefulset and returns the last response body. Controllers
+// and proxies may answer with errors for a while after the objects are created, so it retries
+// until IngressTimeout and only then returns the last error.
+func accessPage(ctx context.Context, httpClient *http.Client, url, host string) (string, error) {
+	timeCh := time.After(IngressTimeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	body, err := probePage(ctx, httpClient, url, host)
+	for err != nil {
+		klog.V(2).Infof("Access to [%s] failed, retrying: %s", url, err.Error())
+		select {
+		case <-ctx.Done():
+			return body, ctx.Err()
+		case <-timeCh:
+			return body, err
+		case <-retryTicker.C:
+			body, err = probePage(ctx, httpClient, url, host)
+		}
+	}
+
+	return body, nil
+}
+
+// probePage sends httpProbeTimes requests to url and fails on the first one which does not
+// return the page served by the statefulset.
+func probePage(ctx context.Context, httpClient *http.Client, url, host string) (string, error) {
+	var body []byte
+	for n := 0; n < httpProbeTimes; n++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+		if host != "" {
+			req.Host = host
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return string(body), fmt.Errorf("unexpected status %d from [%s]", resp.StatusCode, url)
+		}
+		if strings.TrimSpace(string(body)) != Page {
+			return string(body), fmt.Errorf("unexpected page from [%s]", url)
+		}
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,48 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAccessPageRetries(t *testing.T) {
+	// The first requests hit a controller which has not loaded the ingress yet.
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			http.Error(w, "default backend", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, Page)
+	}))
+	defer server.Close()
+
+	body, err := accessPage(context.Background(), server.Client(), server.URL, "")
+	if err != nil || strings.TrimSpace(body) != Page {
+		t.Errorf("accessPage() = %q, %v, want the page", body, err)
+	}
+}
+
+func TestAccessPageTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { IngressTimeout = timeout }(IngressTimeout)
+	IngressTimeout = 100 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	body, err := accessPage(context.Background(), server.Client(), server.URL, "")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 404") {
+		t.Errorf("accessPage() error = %v, want the last status", err)
+	}
+	if !strings.Contains(body, "not found") {
+		t.Errorf("accessPage() body = %q, want the last body", body)
+	}
+}
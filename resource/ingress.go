@@ -2,16 +2,27 @@ package resource
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
 const (
-	annotationKey = "kubernetes.io/ingress.class"
+	annotationKey            = "kubernetes.io/ingress.class"
+	sslRedirectAnnotationKey = "nginx.ingress.kubernetes.io/ssl-redirect"
 )
 
 var (
@@ -20,8 +31,22 @@ var (
 		"component": "k8s-function-checker",
 	}
 	pathType = networkingv1.PathTypePrefix
+
+	ingressControllerLabels = map[string]string{
+		"app.kubernetes.io/name":      "ingress-nginx",
+		"app.kubernetes.io/component": "controller",
+	}
+
+	httpPort  = controllerPort{name: "http", number: 80}
+	httpsPort = controllerPort{name: "https", number: 443}
 )
 
+// controllerPort is a port of the ingress controller service, matched by name or number.
+type controllerPort struct {
+	name   string
+	number int32
+}
+
 var _ OperatorInterface = &Ingress{}
 
 type Ingress struct {
@@ -29,17 +54,24 @@ type Ingress struct {
 	created bool
 }
 
+// RunHost returns the ingress host of the run, the RunID under domain, so that the ingresses
+// of concurrent runs neither conflict nor route to each other.
+func RunHost(domain string) string {
+	return RunID + "." + domain
+}
+
+// NewIngress builds the ingress routing RunHost(domain) to the ClusterIP service.
 func NewIngress(namespace, class, annotationValue, domain string) *Ingress {
 	i := new(Ingress)
 	i.ing = &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "k8s-function-checker-ingress",
+			Name:      objectName("k8s-function-checker-ingress"),
 			Namespace: namespace,
-			Labels:    ingressLabels,
+			Labels:    runLabels(ingressLabels),
 		},
 		Spec: networkingv1.IngressSpec{
 			Rules: []networkingv1.IngressRule{{
-				Host: domain,
+				Host: RunHost(domain),
 				IngressRuleValue: networkingv1.IngressRuleValue{
 					HTTP: &networkingv1.HTTPIngressRuleValue{
 						Paths: []networkingv1.HTTPIngressPath{{
@@ -47,7 +79,7 @@ func NewIngress(namespace, class, annotationValue, domain string) *Ingress {
 							PathType: &pathType,
 							Backend: networkingv1.IngressBackend{
 								Service: &networkingv1.IngressServiceBackend{
-									Name: "k8s-function-checker-svc",
+									Name: objectName(serviceName),
 									Port: networkingv1.ServiceBackendPort{
 										Number: int32(80),
 									},
@@ -74,8 +106,17 @@ func (i *Ingress) FormatedName() string {
 	return strings.Join([]string{i.ing.Namespace, "ingresses", i.ing.Name}, "/")
 }
 
-func (i *Ingress) Create(client *kubernetes.Clientset) error {
-	_, err := client.NetworkingV1().Ingresses(i.ing.Namespace).Create(context.Background(), i.ing, metav1.CreateOptions{})
+func (i *Ingress) Object() runtime.Object {
+	return i.ing
+}
+
+// Host returns the host routed by the ingress.
+func (i *Ingress) Host() string {
+	return i.ing.Spec.Rules[0].Host
+}
+
+func (i *Ingress) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.NetworkingV1().Ingresses(i.ing.Namespace).Create(ctx, i.ing, metav1.CreateOptions{DryRun: DryRun})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -89,8 +130,8 @@ func (i *Ingress) IsCreated() bool {
 	return i.created
 }
 
-func (i *Ingress) Delete(client *kubernetes.Clientset) error {
-	err := client.NetworkingV1().Ingresses(i.ing.Namespace).Delete(context.Background(), i.ing.Name, metav1.DeleteOptions{})
+func (i *Ingress) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.NetworkingV1().Ingresses(i.ing.Namespace).Delete(ctx, i.ing.Name, metav1.DeleteOptions{})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -98,3 +139,257 @@ func (i *Ingress) Delete(client *kubernetes.Clientset) error {
 
 	return nil
 }
+
+// Endpoint resolves the host:port on which the ingress controller serves this ingress over HTTP.
+// An explicit override wins, then the address published in the ingress status if it accepts
+// connections on the standard port, then the LoadBalancer/ExternalIP/NodePort of the controller
+// service in ingressNamespace.
+func (i *Ingress) Endpoint(ctx context.Context, client kubernetes.Interface, ingressNamespace, override string) (string, error) {
+	return i.endpoint(ctx, client, ingressNamespace, override, httpPort)
+}
+
+// TLSEndpoint resolves the host:port on which the ingress controller serves this ingress over
+// HTTPS, the same way Endpoint does.
+func (i *Ingress) TLSEndpoint(ctx context.Context, client kubernetes.Interface, ingressNamespace, override string) (string, error) {
+	return i.endpoint(ctx, client, ingressNamespace, override, httpsPort)
+}
+
+func (i *Ingress) endpoint(ctx context.Context, client kubernetes.Interface, ingressNamespace, override string,
+	port controllerPort) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+
+	// The status only carries the address, controllers exposed through a NodePort service
+	// do not listen on the standard port there.
+	status := i.statusEndpoint(ctx, client, port)
+	if status != "" {
+		err := dialEndpoint(ctx, status)
+		if err == nil {
+			klog.Infof("Get ingress endpoint [%s] from status of [%s]", status, i.FormatedName())
+			return status, nil
+		}
+		klog.Infof("Ingress endpoint [%s] from status of [%s] is not reachable: %s", status, i.FormatedName(), err.Error())
+	}
+
+	endpoint, err := controllerServiceEndpoint(ctx, client, ingressNamespace, port)
+	if err != nil {
+		if status != "" {
+			return status, nil
+		}
+		return "", err
+	}
+	klog.Infof("Get ingress endpoint [%s] from ingress controller service", endpoint)
+
+	return endpoint, nil
+}
+
+// statusEndpoint waits up to IngressStatusTimeout for the controller to publish an address in
+// the ingress status and returns it with the standard port, or "" if none was published.
+func (i *Ingress) statusEndpoint(ctx context.Context, client kubernetes.Interface, port controllerPort) string {
+	timeCh := time.After(IngressStatusTimeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	for {
+		ing, err := client.NetworkingV1().Ingresses(i.ing.Namespace).Get(ctx, i.ing.Name, metav1.GetOptions{})
+		if err == nil {
+			for _, lb := range ing.Status.LoadBalancer.Ingress {
+				if lb.IP != "" {
+					return net.JoinHostPort(lb.IP, strconv.Itoa(int(port.number)))
+				}
+				if lb.Hostname != "" {
+					return net.JoinHostPort(lb.Hostname, strconv.Itoa(int(port.number)))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-timeCh:
+			return ""
+		case <-retryTicker.C:
+		}
+	}
+}
+
+// dialEndpoint checks that endpoint accepts TCP connections.
+func dialEndpoint(ctx context.Context, endpoint string) error {
+	dialer := &net.Dialer{Timeout: httpProbeTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func controllerServiceEndpoint(ctx context.Context, client kubernetes.Interface, ingressNamespace string,
+	want controllerPort) (string, error) {
+	svcs, err := client.CoreV1().Services(ingressNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set(ingressControllerLabels).AsSelector().String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, svc := range svcs.Items {
+		var svcPort *corev1.ServicePort
+		for idx, port := range svc.Spec.Ports {
+			if port.Name == want.name || port.Port == want.number {
+				svcPort = &svc.Spec.Ports[idx]
+				break
+			}
+		}
+		if svcPort == nil {
+			continue
+		}
+		port := strconv.Itoa(int(svcPort.Port))
+
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				return net.JoinHostPort(lb.IP, port), nil
+			}
+			if lb.Hostname != "" {
+				return net.JoinHostPort(lb.Hostname, port), nil
+			}
+		}
+		if len(svc.Spec.ExternalIPs) > 0 {
+			return net.JoinHostPort(svc.Spec.ExternalIPs[0], port), nil
+		}
+		if svcPort.NodePort != 0 {
+			nodeIP, err := nodeAddress(ctx, client)
+			if err != nil {
+				return "", err
+			}
+			return net.JoinHostPort(nodeIP, strconv.Itoa(int(svcPort.NodePort))), nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot find ingress controller service in namespace [%s], "+
+		"please use --ingress-endpoint to specify it", ingressNamespace)
+}
+
+func nodeAddress(ctx context.Context, client kubernetes.Interface) (string, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, addrType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, node := range nodes.Items {
+			if !IsNodeReady(&node) {
+				continue
+			}
+			for _, addr := range node.Status.Addresses {
+				if addr.Type == addrType {
+					return addr.Address, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("cannot find address of any ready node")
+}
+
+// IsNodeReady reports whether the Ready condition of node is true.
+func IsNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// AccessFromExternal sends requests with the ingress host to endpoint, expects the page served
+// by the statefulset and returns the last response body.
+func (i *Ingress) AccessFromExternal(ctx context.Context, endpoint string) (string, error) {
+	host := i.Host()
+	url := fmt.Sprintf("http://%s/", endpoint)
+	klog.Infof("Test access to ingress [%s] through [%s] with host [%s]", i.FormatedName(), url, host)
+
+	return accessPage(ctx, &http.Client{Timeout: httpProbeTimeout}, url, host)
+}
+
+// EnableTLS adds a tls block for the ingress host served with the certificate in secret.
+// HTTP requests are still served rather than redirected, so that the plain ingress check
+// keeps working.
+func (i *Ingress) EnableTLS(secret *TLSSecret) {
+	i.ing.Spec.TLS = []networkingv1.IngressTLS{{
+		Hosts:      []string{i.Host()},
+		SecretName: secret.Name(),
+	}}
+	if i.ing.Annotations == nil {
+		i.ing.Annotations = map[string]string{}
+	}
+	i.ing.Annotations[sslRedirectAnnotationKey] = "false"
+}
+
+// AccessFromExternalTLS verifies that the certificate served for the ingress host on endpoint
+// chains up to roots, then requests the page over HTTPS. It returns a description of the
+// served certificate and the last response body.
+func (i *Ingress) AccessFromExternalTLS(ctx context.Context, endpoint string, roots *x509.CertPool) (string, string, error) {
+	host := i.Host()
+	klog.Infof("Test TLS termination of ingress [%s] through [%s] with host [%s]", i.FormatedName(), endpoint, host)
+
+	// The controller may serve its default certificate until it has loaded the secret.
+	timeCh := time.After(IngressTimeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	served, err := verifyServedCertificate(ctx, endpoint, host, roots)
+	for err != nil {
+		select {
+		case <-ctx.Done():
+			return served, "", ctx.Err()
+		case <-timeCh:
+			return served, "", err
+		case <-retryTicker.C:
+			served, err = verifyServedCertificate(ctx, endpoint, host, roots)
+		}
+	}
+
+	httpClient := &http.Client{
+		Timeout: httpProbeTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: host, RootCAs: roots},
+		},
+	}
+	body, err := accessPage(ctx, httpClient, fmt.Sprintf("https://%s/", endpoint), host)
+
+	return served, body, err
+}
+
+// verifyServedCertificate verifies the certificate served for host on endpoint against roots
+// and returns a description of it.
+func verifyServedCertificate(ctx context.Context, endpoint, host string, roots *x509.CertPool) (string, error) {
+	// Skip verification during the handshake to report what was served, it is verified below.
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: httpProbeTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return "", err
+	}
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	conn.Close()
+	if len(chain) == 0 {
+		return "", fmt.Errorf("no certificate served on [%s]", endpoint)
+	}
+
+	leaf := chain[0]
+	served := fmt.Sprintf("subject=%s issuer=%s notAfter=%s", leaf.Subject, leaf.Issuer, leaf.NotAfter.Format(time.RFC3339))
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: intermediates}); err != nil {
+		return served, fmt.Errorf("certificate served for [%s] is not the generated one: %v", host, err)
+	}
+
+	return served, nil
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIngressEndpoint(t *testing.T) {
+	defer func(timeout time.Duration) { IngressStatusTimeout = timeout }(IngressStatusTimeout)
+	IngressStatusTimeout = 100 * time.Millisecond
+
+	// The controller is only reachable on its node port, as with a NodePort service.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	nodePort, _ := strconv.Atoi(portStr)
+	reachable := "127.0.0.1:" + portStr
+
+	// A closed port, as the standard port on a node which only exposes the node port.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	controller := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress-nginx-controller", Namespace: "ingress-nginx", Labels: ingressControllerLabels},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: int32(nodePort)}},
+		},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "127.0.0.1"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		port   controllerPort
+		want   string
+	}{
+		{name: "reachable status", status: "127.0.0.1", port: controllerPort{name: "http", number: int32(nodePort)}, want: reachable},
+		{name: "unreachable status", status: "127.0.0.1", port: controllerPort{name: "http", number: int32(closedPort)}, want: reachable},
+		{name: "no status", port: controllerPort{name: "http", number: 80}, want: reachable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := NewIngress(testNamespace, "nginx", "nginx", "nginx-test.example.com")
+			obj := ing.Object().(*networkingv1.Ingress).DeepCopy()
+			if tt.status != "" {
+				obj.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: tt.status}}
+			}
+			client := fake.NewSimpleClientset(obj, controller, node)
+
+			start := time.Now()
+			got, err := ing.endpoint(context.Background(), client, "ingress-nginx", "", tt.port)
+			if err != nil {
+				t.Fatalf("endpoint() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("endpoint() = %s, want %s", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("endpoint() took %s, want the status wait bounded by IngressStatusTimeout", elapsed)
+			}
+		})
+	}
+}
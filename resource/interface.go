@@ -1,18 +1,72 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"k8s.io/klog/v2"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// RunIDLabel is stamped on every object of a run with the RunID.
+	RunIDLabel = "run-id"
+
+	runIDLength = 6
+)
+
+// ComponentSelector selects every object created by the checker, whichever run created it.
+var ComponentSelector = labels.FormatLabels(map[string]string{
+	"component": "k8s-function-checker",
+})
+
+// RunID identifies the objects of the current run, so that runs sharing a namespace do not
+// collide. It is suffixed to the name of every object and stamped as RunIDLabel, and must
+// be set before any resource is built.
+var RunID = NewRunID()
+
+// NewRunID generates a run ID, it is short and valid in names and label values.
+func NewRunID() string {
+	return utilrand.String(runIDLength)
+}
+
+// RunSelector selects the objects created by the current run.
+func RunSelector() string {
+	return SelectorForRun(RunID)
+}
+
+// SelectorForRun selects the objects created by the run identified by runID.
+func SelectorForRun(runID string) string {
+	return labels.FormatLabels(map[string]string{"component": "k8s-function-checker", RunIDLabel: runID})
+}
+
+// objectName suffixes base with the RunID.
+func objectName(base string) string {
+	return base + "-" + RunID
+}
+
+// runLabels returns a copy of l stamped with the RunID.
+func runLabels(l map[string]string) map[string]string {
+	stamped := make(map[string]string, len(l)+1)
+	for k, v := range l {
+		stamped[k] = v
+	}
+	stamped[RunIDLabel] = RunID
+
+	return stamped
+}
+
 type OperatorInterface interface {
 	FormatedName() string
-	Create(client *kubernetes.Clientset) error
+	// Object returns the object submitted by Create.
+	Object() runtime.Object
+	Create(ctx context.Context, client kubernetes.Interface) error
 	IsCreated() bool
-	Delete(client *kubernetes.Clientset) error
+	Delete(ctx context.Context, client kubernetes.Interface) error
 }
 
 type Operators struct {
@@ -23,10 +77,21 @@ func (ops *Operators) Add(r ...OperatorInterface) {
 	ops.ops = append(ops.ops, r...)
 }
 
-func (ops *Operators) Create(client *kubernetes.Clientset) error {
+// Created returns the resources which were created and not deleted since.
+func (ops *Operators) Created() []OperatorInterface {
+	var created []OperatorInterface
+	for _, r := range ops.ops {
+		if r.IsCreated() {
+			created = append(created, r)
+		}
+	}
+	return created
+}
+
+func (ops *Operators) Create(ctx context.Context, client kubernetes.Interface) error {
 	var allErrs []error
 	for _, r := range ops.ops {
-		err := r.Create(client)
+		err := r.Create(ctx, client)
 		if err != nil {
 			allErrs = append(allErrs, fmt.Errorf("error creating resource: %v ", err))
 			continue
@@ -36,14 +101,17 @@ func (ops *Operators) Create(client *kubernetes.Clientset) error {
 	return utilerrors.NewAggregate(allErrs)
 }
 
-func (ops *Operators) Delete(client *kubernetes.Clientset) error {
+// Delete deletes the created resources in the reverse order of their creation, so that a
+// resource is gone before those it refers to.
+func (ops *Operators) Delete(ctx context.Context, client kubernetes.Interface) error {
 	var allErrs []error
-	for _, r := range ops.ops {
+	for i := len(ops.ops) - 1; i >= 0; i-- {
+		r := ops.ops[i]
 		{
 			if r.IsCreated() {
-				err := r.Delete(client)
+				err := r.Delete(ctx, client)
 				if err != nil {
-					allErrs = append(allErrs, fmt.Errorf("error creating resource: %v ", err))
+					allErrs = append(allErrs, fmt.Errorf("error deleting resource: %v ", err))
 					continue
 				}
 				klog.Infof("Resource [%s] delete successfully", r.FormatedName())
@@ -0,0 +1,212 @@
+package resource
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testNamespace = "function-check"
+	testRunID     = "abc123"
+)
+
+func init() {
+	RunID = testRunID
+}
+
+func getObject(client kubernetes.Interface, op OperatorInterface) error {
+	ctx := context.Background()
+	switch r := op.(type) {
+	case *ConfigMap:
+		_, err := client.CoreV1().ConfigMaps(r.cm.Namespace).Get(ctx, r.cm.Name, metav1.GetOptions{})
+		return err
+	case *Service:
+		_, err := client.CoreV1().Services(r.svc.Namespace).Get(ctx, r.svc.Name, metav1.GetOptions{})
+		return err
+	case *StatefulSet:
+		_, err := client.AppsV1().StatefulSets(r.sts.Namespace).Get(ctx, r.sts.Name, metav1.GetOptions{})
+		return err
+	case *Ingress:
+		_, err := client.NetworkingV1().Ingresses(r.ing.Namespace).Get(ctx, r.ing.Name, metav1.GetOptions{})
+		return err
+	case *DaemonSet:
+		_, err := client.AppsV1().DaemonSets(r.ds.Namespace).Get(ctx, r.ds.Name, metav1.GetOptions{})
+		return err
+	case *ClientPod:
+		_, err := client.CoreV1().Pods(r.pod.Namespace).Get(ctx, r.pod.Name, metav1.GetOptions{})
+		return err
+	case *NetworkPolicy:
+		_, err := client.NetworkingV1().NetworkPolicies(r.np.Namespace).Get(ctx, r.np.Name, metav1.GetOptions{})
+		return err
+	case *RoleBinding:
+		_, err := client.RbacV1().RoleBindings(r.rb.Namespace).Get(ctx, r.rb.Name, metav1.GetOptions{})
+		return err
+	}
+	return nil
+}
+
+func newTestOperators() []OperatorInterface {
+	return []OperatorInterface{
+		NewConfigMap(testNamespace),
+		NewRoleBinding(testNamespace, "k8s-function-checker-function-check-namespace", "function-check", "k8s-function-checker"),
+		NewService(testNamespace),
+		NewHeadlessService(testNamespace),
+		NewNodePortService(testNamespace),
+		NewLoadBalancerService(testNamespace),
+		NewExternalNameService(testNamespace, "k8s-function-checker-svc.function-check.svc.cluster.local"),
+		NewStatefulSet(testNamespace, "standard", apiresource.MustParse("1Gi")),
+		NewIngress(testNamespace, "nginx", "nginx", "nginx-test.example.com"),
+		NewDaemonSet(testNamespace, false),
+		NewClientPod(testNamespace),
+		NewDenyIngressPolicy(testNamespace),
+		NewAllowClientPolicy(testNamespace),
+	}
+}
+
+func TestOperatorCreateDelete(t *testing.T) {
+	for _, op := range newTestOperators() {
+		t.Run(op.FormatedName(), func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+
+			if op.IsCreated() {
+				t.Fatalf("IsCreated() = true before Create")
+			}
+			if err := op.Create(context.Background(), client); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if !op.IsCreated() {
+				t.Fatalf("IsCreated() = false after Create")
+			}
+			if err := getObject(client, op); err != nil {
+				t.Fatalf("object not found after Create: %v", err)
+			}
+
+			if err := op.Create(context.Background(), client); !apierrors.IsAlreadyExists(err) {
+				t.Fatalf("second Create() error = %v, want AlreadyExists", err)
+			}
+
+			if err := op.Delete(context.Background(), client); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := getObject(client, op); !apierrors.IsNotFound(err) {
+				t.Fatalf("object still exists after Delete: %v", err)
+			}
+		})
+	}
+}
+
+func TestOperatorsCreateDelete(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	rs := new(Operators)
+	ops := newTestOperators()
+	rs.Add(ops...)
+
+	if err := rs.Create(context.Background(), client); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, op := range ops {
+		if !op.IsCreated() {
+			t.Errorf("%s: IsCreated() = false after Create", op.FormatedName())
+		}
+	}
+
+	client.ClearActions()
+	if err := rs.Delete(context.Background(), client); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	for _, op := range ops {
+		if err := getObject(client, op); !apierrors.IsNotFound(err) {
+			t.Errorf("%s: object still exists after Delete: %v", op.FormatedName(), err)
+		}
+	}
+
+	// Resources are deleted in the reverse order of their creation.
+	var deleted []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "delete" {
+			deleted = append(deleted, action.GetResource().Resource)
+		}
+	}
+	if len(deleted) == 0 || deleted[0] != "networkpolicies" || deleted[len(deleted)-1] != "configmaps" {
+		t.Errorf("deleted %v, want networkpolicies first and configmaps last", deleted)
+	}
+}
+
+func TestOperatorsCreated(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cm := NewConfigMap(testNamespace)
+	policy := NewDenyIngressPolicy(testNamespace)
+	rs := new(Operators)
+	rs.Add(cm, NewService(testNamespace), policy)
+
+	for _, op := range []OperatorInterface{cm, policy} {
+		if err := op.Create(context.Background(), client); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	// A policy deleted by its check is not left in place.
+	if err := policy.Delete(context.Background(), client); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	created := rs.Created()
+	if len(created) != 1 || created[0] != cm {
+		t.Errorf("Created() = %v, want only the configmap", created)
+	}
+}
+
+func TestOperatorsCreateAggregatesErrors(t *testing.T) {
+	cm := NewConfigMap(testNamespace)
+	client := fake.NewSimpleClientset(cm.cm.DeepCopy())
+
+	rs := new(Operators)
+	svc := NewService(testNamespace)
+	rs.Add(cm, svc)
+
+	if err := rs.Create(context.Background(), client); err == nil {
+		t.Fatalf("Create() error = nil, want AlreadyExists for configmap")
+	}
+	if cm.IsCreated() {
+		t.Errorf("configmap IsCreated() = true after failed Create")
+	}
+	if !svc.IsCreated() {
+		t.Errorf("service IsCreated() = false, creation should continue after an error")
+	}
+
+	// Only created resources are deleted, the pre-existing configmap is left alone.
+	if err := rs.Delete(context.Background(), client); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := getObject(client, cm); err != nil {
+		t.Errorf("pre-existing configmap was deleted: %v", err)
+	}
+}
+
+func TestNewIngressClass(t *testing.T) {
+	tests := []struct {
+		name, class, annotation string
+		wantClassName           bool
+		wantAnnotation          bool
+	}{
+		{name: "ingressclass", class: "nginx", annotation: "nginx", wantClassName: true},
+		{name: "annotation", annotation: "nginx", wantAnnotation: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := NewIngress(testNamespace, tt.class, tt.annotation, "nginx-test.example.com")
+			if got := ing.ing.Spec.IngressClassName != nil; got != tt.wantClassName {
+				t.Errorf("IngressClassName set = %v, want %v", got, tt.wantClassName)
+			}
+			if _, got := ing.ing.Annotations[annotationKey]; got != tt.wantAnnotation {
+				t.Errorf("annotation set = %v, want %v", got, tt.wantAnnotation)
+			}
+		})
+	}
+}
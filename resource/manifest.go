@@ -0,0 +1,60 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// WriteManifests prints the objects of ops as a multi-document YAML stream, as they would
+// be submitted on create.
+func WriteManifests(w io.Writer, ops []OperatorInterface) error {
+	for _, op := range ops {
+		u, err := toUnstructured(op.Object())
+		if err != nil {
+			return fmt.Errorf("render [%s]: %v", op.FormatedName(), err)
+		}
+		data, err := yaml.Marshal(u.Object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toUnstructured converts obj, setting the apiVersion and kind which typed objects leave empty.
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvks[0])
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "spec", "template", "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+	if claims, ok, _ := unstructured.NestedSlice(u.Object, "spec", "volumeClaimTemplates"); ok {
+		for _, claim := range claims {
+			unstructured.RemoveNestedField(claim.(map[string]interface{}), "metadata", "creationTimestamp")
+			unstructured.RemoveNestedField(claim.(map[string]interface{}), "status")
+		}
+		if err := unstructured.SetNestedSlice(u.Object, claims, "spec", "volumeClaimTemplates"); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
@@ -0,0 +1,36 @@
+package resource
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteManifests(t *testing.T) {
+	var out bytes.Buffer
+	ops := []OperatorInterface{NewNamespace(""), NewConfigMap(testNamespace), newTestStatefulSet(), NewDenyIngressPolicy(testNamespace)}
+	if err := WriteManifests(&out, ops); err != nil {
+		t.Fatalf("WriteManifests() error = %v", err)
+	}
+
+	docs := strings.Split(strings.TrimPrefix(out.String(), "---\n"), "---\n")
+	if len(docs) != len(ops) {
+		t.Fatalf("WriteManifests() wrote %d documents, want %d", len(docs), len(ops))
+	}
+	for i, want := range []string{
+		"apiVersion: v1\nkind: Namespace\n",
+		"apiVersion: v1\n",
+		"apiVersion: apps/v1\n",
+		"apiVersion: networking.k8s.io/v1\n",
+	} {
+		if !strings.HasPrefix(docs[i], want) {
+			t.Errorf("document %d = %q, want it to start with %q", i, docs[i], want)
+		}
+	}
+	if !strings.Contains(docs[2], "kind: StatefulSet\n") || !strings.Contains(docs[2], "name: k8s-function-checker-sts-"+testRunID) {
+		t.Errorf("statefulset document = %s", docs[2])
+	}
+	if strings.Contains(out.String(), "status:") {
+		t.Errorf("WriteManifests() should not render status:\n%s", out.String())
+	}
+}
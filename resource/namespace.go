@@ -0,0 +1,167 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// PodSecurityEnforceLabel sets the Pod Security Admission level enforced in a namespace.
+	PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+)
+
+var (
+	nsLabels = map[string]string{
+		"kind":      "namespace",
+		"component": "k8s-function-checker",
+	}
+
+	// PodSecurityLevels are the levels of Pod Security Admission the pods of the checker are
+	// admitted at. restricted is left out, Image runs nginx as root on port 80.
+	PodSecurityLevels = []string{"privileged", "baseline"}
+
+	// namespaceDeletionConditions explain why the deletion of a namespace does not finish.
+	namespaceDeletionConditions = []corev1.NamespaceConditionType{
+		corev1.NamespaceDeletionDiscoveryFailure,
+		corev1.NamespaceDeletionContentFailure,
+		corev1.NamespaceDeletionGVParsingFailure,
+		corev1.NamespaceContentRemaining,
+		corev1.NamespaceFinalizersRemaining,
+	}
+)
+
+var _ OperatorInterface = &Namespace{}
+
+// Namespace is created for a single run and holds every resource of it, deleting it
+// removes them all at once.
+type Namespace struct {
+	ns      *corev1.Namespace
+	created bool
+}
+
+// NewNamespace builds a namespace named after the run, enforcing podSecurityLevel
+// unless it is empty.
+func NewNamespace(podSecurityLevel string) *Namespace {
+	n := new(Namespace)
+
+	n.ns = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   objectName("k8s-function-checker"),
+			Labels: runLabels(nsLabels),
+		},
+	}
+	if podSecurityLevel != "" {
+		n.ns.Labels[PodSecurityEnforceLabel] = podSecurityLevel
+	}
+
+	return n
+}
+
+func (n *Namespace) FormatedName() string {
+	return strings.Join([]string{"namespaces", n.ns.Name}, "/")
+}
+
+func (n *Namespace) Object() runtime.Object {
+	return n.ns
+}
+
+func (n *Namespace) Name() string {
+	return n.ns.Name
+}
+
+func (n *Namespace) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.CoreV1().Namespaces().Create(ctx, n.ns, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	n.created = true
+
+	return nil
+}
+
+func (n *Namespace) IsCreated() bool {
+	return n.created
+}
+
+func (n *Namespace) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().Namespaces().Delete(ctx, n.ns.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// WaitForDeleted waits until the namespace is finalized and gone. On timeout the returned
+// error names the finalizers and the content keeping it in Terminating.
+func (n *Namespace) WaitForDeleted(ctx context.Context, client kubernetes.Interface, timeout time.Duration) error {
+	klog.Infof("Waiting for [%s] to be deleted", n.FormatedName())
+
+	timeCh := time.After(timeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	var last *corev1.Namespace
+	for {
+		ns, err := client.CoreV1().Namespaces().Get(ctx, n.ns.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			klog.Infoln(err.Error())
+		default:
+			last = ns
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeCh:
+			if last == nil {
+				return fmt.Errorf("[%s] not deleted after %s", n.FormatedName(), timeout)
+			}
+			return fmt.Errorf("[%s] not deleted after %s: %s", n.FormatedName(), timeout,
+				strings.Join(describeStuckNamespace(last), ", "))
+		case <-retryTicker.C:
+		}
+	}
+}
+
+// describeStuckNamespace explains why ns is still terminating, from its finalizers and the
+// conditions set by the namespace controller.
+func describeStuckNamespace(ns *corev1.Namespace) []string {
+	var reasons []string
+	if len(ns.Spec.Finalizers) > 0 {
+		var finalizers []string
+		for _, f := range ns.Spec.Finalizers {
+			finalizers = append(finalizers, string(f))
+		}
+		reasons = append(reasons, fmt.Sprintf("finalizers [%s] remaining", strings.Join(finalizers, " ")))
+	}
+	if len(ns.Finalizers) > 0 {
+		reasons = append(reasons, fmt.Sprintf("metadata finalizers [%s] remaining", strings.Join(ns.Finalizers, " ")))
+	}
+	for _, t := range namespaceDeletionConditions {
+		for _, cond := range ns.Status.Conditions {
+			if cond.Type == t && cond.Status == corev1.ConditionTrue {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", cond.Reason, cond.Message))
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, fmt.Sprintf("phase is %s", ns.Status.Phase))
+	}
+
+	return reasons
+}
@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewNamespace(t *testing.T) {
+	n := NewNamespace("baseline")
+	if n.Name() != "k8s-function-checker-"+testRunID {
+		t.Errorf("Name() = %s, want it suffixed with the run id", n.Name())
+	}
+	if got := n.ns.Labels[PodSecurityEnforceLabel]; got != "baseline" {
+		t.Errorf("label %s = %q, want baseline", PodSecurityEnforceLabel, got)
+	}
+	if got := n.ns.Labels[RunIDLabel]; got != testRunID {
+		t.Errorf("label %s = %q, want %s", RunIDLabel, got, testRunID)
+	}
+
+	if _, ok := NewNamespace("").ns.Labels[PodSecurityEnforceLabel]; ok {
+		t.Errorf("label %s set without a level", PodSecurityEnforceLabel)
+	}
+}
+
+func TestNamespaceWaitForDeleted(t *testing.T) {
+	n := NewNamespace("")
+
+	client := fake.NewSimpleClientset()
+	if err := n.WaitForDeleted(context.Background(), client, time.Second); err != nil {
+		t.Errorf("WaitForDeleted() error = %v", err)
+	}
+
+	stuck := n.ns.DeepCopy()
+	stuck.Spec.Finalizers = []corev1.FinalizerName{corev1.FinalizerKubernetes}
+	stuck.Status = corev1.NamespaceStatus{
+		Phase: corev1.NamespaceTerminating,
+		Conditions: []corev1.NamespaceCondition{{
+			Type:    corev1.NamespaceFinalizersRemaining,
+			Status:  corev1.ConditionTrue,
+			Reason:  "SomeFinalizersRemain",
+			Message: "Some content in the namespace has finalizers remaining: kubernetes.io/pvc-protection in 3 resource instances",
+		}, {
+			Type:   corev1.NamespaceDeletionDiscoveryFailure,
+			Status: corev1.ConditionFalse,
+			Reason: "ResourcesDiscovered",
+		}},
+	}
+	client = fake.NewSimpleClientset(stuck)
+	err := n.WaitForDeleted(context.Background(), client, 100*time.Millisecond)
+	if err == nil {
+		t.Fatalf("WaitForDeleted() error = nil, want stuck finalizers")
+	}
+	for _, want := range []string{
+		"finalizers [kubernetes] remaining",
+		"SomeFinalizersRemain: Some content in the namespace has finalizers remaining: kubernetes.io/pvc-protection",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("WaitForDeleted() error = %q, want it to contain %q", err.Error(), want)
+		}
+	}
+	if strings.Contains(err.Error(), "ResourcesDiscovered") {
+		t.Errorf("WaitForDeleted() error = %q, should not report conditions which are not true", err.Error())
+	}
+}
@@ -0,0 +1,114 @@
+package resource
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var (
+	netpolLabels = map[string]string{
+		"kind":      "networkpolicy",
+		"component": "k8s-function-checker",
+	}
+)
+
+var _ OperatorInterface = &NetworkPolicy{}
+
+type NetworkPolicy struct {
+	np      *networkingv1.NetworkPolicy
+	created bool
+}
+
+// NewDenyIngressPolicy builds a policy denying all ingress traffic to the pods of the run.
+func NewDenyIngressPolicy(namespace string) *NetworkPolicy {
+	p := new(NetworkPolicy)
+
+	p.np = &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker-deny-ingress"),
+			Namespace: namespace,
+			Labels:    runLabels(netpolLabels),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: runLabels(map[string]string{"component": "k8s-function-checker"}),
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+
+	return p
+}
+
+// NewAllowClientPolicy builds a policy allowing the client pod to reach port 80 of the statefulset pods.
+func NewAllowClientPolicy(namespace string) *NetworkPolicy {
+	p := new(NetworkPolicy)
+	protocol := corev1.ProtocolTCP
+	port := intstr.FromInt(80)
+
+	p.np = &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker-allow-client"),
+			Namespace: namespace,
+			Labels:    runLabels(netpolLabels),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: runLabels(stsLabels),
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					PodSelector: &metav1.LabelSelector{MatchLabels: runLabels(clientLabels)},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &port}},
+			}},
+		},
+	}
+
+	return p
+}
+
+func (p *NetworkPolicy) FormatedName() string {
+	return strings.Join([]string{p.np.Namespace, "networkpolicies", p.np.Name}, "/")
+}
+
+func (p *NetworkPolicy) Object() runtime.Object {
+	return p.np
+}
+
+func (p *NetworkPolicy) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.NetworkingV1().NetworkPolicies(p.np.Namespace).Create(ctx, p.np, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	p.created = true
+
+	return nil
+}
+
+func (p *NetworkPolicy) IsCreated() bool {
+	return p.created
+}
+
+// Delete removes the policy. Unlike the shared resources policies are deleted as soon as their
+// check ends, so created is reset to keep cleanup from deleting them again.
+func (p *NetworkPolicy) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.NetworkingV1().NetworkPolicies(p.np.Namespace).Delete(ctx, p.np.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	p.created = false
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package resource
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNetworkPolicyDeletedOnce(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	deny := NewDenyIngressPolicy(testNamespace)
+
+	ops := new(Operators)
+	ops.Add(deny)
+	if err := deny.Create(context.Background(), client); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := deny.Delete(context.Background(), client); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if deny.IsCreated() {
+		t.Errorf("IsCreated() = true after Delete")
+	}
+
+	if err := ops.Delete(context.Background(), client); err != nil {
+		t.Errorf("Operators.Delete() error = %v, a deleted policy should be skipped", err)
+	}
+}
@@ -0,0 +1,97 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IsPodReady reports whether the Ready condition of pod is true.
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// podNotReadyReasons explains why pod is not ready: not scheduled, image cannot be pulled,
+// volume claims are unbound or containers are waiting for other reasons.
+func podNotReadyReasons(ctx context.Context, client kubernetes.Interface, pod *corev1.Pod) []string {
+	var reasons []string
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status != corev1.ConditionTrue {
+			reasons = append(reasons, fmt.Sprintf("pending scheduling: %s", cond.Message))
+		}
+	}
+
+	for _, vol := range pod.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc, err := client.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, vol.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("persistentvolumeclaim [%s]: %v", vol.PersistentVolumeClaim.ClaimName, err))
+			continue
+		}
+		if pvc.Status.Phase != corev1.ClaimBound {
+			reasons = append(reasons, fmt.Sprintf("unbound persistentvolumeclaim [%s] is %s", pvc.Name, pvc.Status.Phase))
+		}
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		switch cs.State.Waiting.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+			reasons = append(reasons, fmt.Sprintf("image pull of container [%s]: %s %s",
+				cs.Name, cs.State.Waiting.Reason, cs.State.Waiting.Message))
+		default:
+			reasons = append(reasons, fmt.Sprintf("container [%s] waiting: %s %s",
+				cs.Name, cs.State.Waiting.Reason, cs.State.Waiting.Message))
+		}
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, fmt.Sprintf("phase is %s", pod.Status.Phase))
+	}
+
+	return reasons
+}
+
+// describeNotReadyPods lists the pods matched by selector and explains each expected pod which is not ready.
+func describeNotReadyPods(ctx context.Context, client kubernetes.Interface, namespace, selector string, expected []string) ([]string, error) {
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		byName[pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	var notReady []string
+	for _, name := range expected {
+		pod, ok := byName[name]
+		if !ok {
+			notReady = append(notReady, fmt.Sprintf("pod [%s]: not created", name))
+			continue
+		}
+		if IsPodReady(pod) {
+			continue
+		}
+		notReady = append(notReady, fmt.Sprintf("pod [%s]: %s", name,
+			strings.Join(podNotReadyReasons(ctx, client, pod), "; ")))
+	}
+
+	return notReady, nil
+}
@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var (
+	roleBindingLabels = map[string]string{
+		"kind":      "rolebinding",
+		"component": "k8s-function-checker",
+	}
+)
+
+var _ OperatorInterface = &RoleBinding{}
+
+// RoleBinding grants a ServiceAccount a ClusterRole inside the namespace of the run only, so
+// that what the checks need is not granted in every namespace.
+type RoleBinding struct {
+	rb      *rbacv1.RoleBinding
+	created bool
+}
+
+// NewRoleBinding builds a binding of clusterRole to the ServiceAccount saName in saNamespace,
+// limited to namespace.
+func NewRoleBinding(namespace, clusterRole, saNamespace, saName string) *RoleBinding {
+	r := new(RoleBinding)
+
+	r.rb = &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker"),
+			Namespace: namespace,
+			Labels:    runLabels(roleBindingLabels),
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      saName,
+			Namespace: saNamespace,
+		}},
+	}
+
+	return r
+}
+
+func (r *RoleBinding) FormatedName() string {
+	return strings.Join([]string{r.rb.Namespace, "rolebindings", r.rb.Name}, "/")
+}
+
+func (r *RoleBinding) Object() runtime.Object {
+	return r.rb
+}
+
+func (r *RoleBinding) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.RbacV1().RoleBindings(r.rb.Namespace).Create(ctx, r.rb, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	r.created = true
+
+	return nil
+}
+
+func (r *RoleBinding) IsCreated() bool {
+	return r.created
+}
+
+func (r *RoleBinding) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.RbacV1().RoleBindings(r.rb.Namespace).Delete(ctx, r.rb.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// WaitForGranted waits until the caller is allowed to create configmaps in the namespace of
+// the binding, as the authorizer picks up a new binding with a delay.
+func (r *RoleBinding) WaitForGranted(ctx context.Context, client kubernetes.Interface, timeout time.Duration) error {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: r.rb.Namespace,
+				Verb:      "create",
+				Resource:  "configmaps",
+			},
+		},
+	}
+
+	timeCh := time.After(timeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	for {
+		resp, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			klog.Infoln(err.Error())
+		} else if resp.Status.Allowed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeCh:
+			return fmt.Errorf("[%s] does not grant access after %s", r.FormatedName(), timeout)
+		case <-retryTicker.C:
+		}
+	}
+}
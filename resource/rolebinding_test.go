@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRoleBindingWaitForGranted(t *testing.T) {
+	rb := NewRoleBinding(testNamespace, "k8s-function-checker-function-check-namespace", "function-check", "k8s-function-checker")
+	if got := rb.rb.Subjects[0]; got.Namespace != "function-check" || got.Name != "k8s-function-checker" {
+		t.Errorf("subject = %+v, want the ServiceAccount function-check/k8s-function-checker", got)
+	}
+
+	for _, allowed := range []bool{true, false} {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			if review.Spec.ResourceAttributes.Namespace != testNamespace {
+				t.Errorf("review of namespace [%s], want [%s]", review.Spec.ResourceAttributes.Namespace, testNamespace)
+			}
+			review.Status.Allowed = allowed
+			return true, review, nil
+		})
+
+		err := rb.WaitForGranted(context.Background(), client, 100*time.Millisecond)
+		if allowed && err != nil {
+			t.Errorf("WaitForGranted() error = %v", err)
+		}
+		if !allowed && (err == nil || !strings.Contains(err.Error(), "does not grant access")) {
+			t.Errorf("WaitForGranted() error = %v, want timeout", err)
+		}
+	}
+}
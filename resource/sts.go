@@ -1,7 +1,6 @@
 package resource
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"k8s.io/apimachinery/pkg/labels"
@@ -13,22 +12,46 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/klog/v2"
-	"k8s.io/kubectl/pkg/scheme"
 )
 
 const (
-	//dockerImage = "reg.kolla.org/library/nginx:1.21.4"
-	dockerImage = "registry.cn-shanghai.aliyuncs.com/ltzhang/nginx:1.21.4"
-	waitTimeout = time.Duration(300) * time.Second
-	waitTicker  = time.Duration(2) * time.Second
+	waitTicker = time.Duration(2) * time.Second
+
+	// ContainerName is the name of the nginx container in statefulset pods.
+	ContainerName = "function-check-container"
+	// DataMountPath is where the persistentvolumeclaim is mounted in statefulset pods.
+	DataMountPath = "/opt"
+)
+
+// Tunables of the resources, they are bound to command line flags and must be set before
+// any resource is built.
+var (
+	// Image is run by every pod of the checker, it must provide nginx, curl and getent.
+	//Image = "reg.kolla.org/library/nginx:1.21.4"
+	Image = "registry.cn-shanghai.aliyuncs.com/ltzhang/nginx:1.21.4"
+	// Replicas is the number of statefulset replicas.
+	Replicas = int32(3)
+	// IngressTimeout is how long to wait for the ingress controller to serve the page and
+	// the generated certificate.
+	IngressTimeout = time.Duration(30) * time.Second
+	// IngressStatusTimeout is how long to wait for the ingress controller to publish an address
+	// in the ingress status before the address of its service is used instead.
+	IngressStatusTimeout = time.Duration(10) * time.Second
+	// DryRun is passed on every create, set it to metav1.DryRunAll to submit the resources
+	// with server-side dry run, so that admission and quotas are exercised without persisting them.
+	DryRun []string
 )
 
 var (
-	replicas  = int32(3)
 	stsLabels = map[string]string{
 		"kind":      "statefulset",
 		"component": "k8s-function-checker",
@@ -44,22 +67,24 @@ type StatefulSet struct {
 
 func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity) *StatefulSet {
 	s := new(StatefulSet)
+	replicas := Replicas
+	podLabels := runLabels(stsLabels)
 
 	s.sts = &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "k8s-function-checker-sts",
+			Name:      objectName("k8s-function-checker-sts"),
 			Namespace: namespace,
-			Labels:    stsLabels,
+			Labels:    podLabels,
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: stsLabels,
+				MatchLabels: podLabels,
 			},
-			ServiceName: "k8s-function-checker-svc",
+			ServiceName: objectName(headlessServiceName),
 			Replicas:    &replicas,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: stsLabels,
+					Labels: podLabels,
 				},
 				Spec: corev1.PodSpec{
 					Affinity: &corev1.Affinity{
@@ -72,6 +97,10 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 											Key:      "component",
 											Operator: metav1.LabelSelectorOpIn,
 											Values:   []string{"k8s-function-checker"},
+										}, {
+											Key:      RunIDLabel,
+											Operator: metav1.LabelSelectorOpIn,
+											Values:   []string{RunID},
 										}},
 									},
 									TopologyKey: "kubernetes.io/hostname",
@@ -79,13 +108,13 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 							}}}},
 					Containers: []corev1.Container{
 						{
-							Name:  "function-check-container",
-							Image: dockerImage,
+							Name:  ContainerName,
+							Image: Image,
 							Ports: []corev1.ContainerPort{{
 								ContainerPort: int32(80),
 							}},
 							VolumeMounts: []corev1.VolumeMount{
-								{Name: "pvc", MountPath: "/opt"},
+								{Name: "pvc", MountPath: DataMountPath},
 								{Name: "webpage", MountPath: "/usr/share/nginx/html"},
 								{Name: "script", MountPath: "/script"},
 							},
@@ -97,7 +126,7 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 							VolumeSource: corev1.VolumeSource{
 								ConfigMap: &corev1.ConfigMapVolumeSource{
 									LocalObjectReference: corev1.LocalObjectReference{
-										Name: "k8s-function-checker-cm",
+										Name: objectName(configMapName),
 									},
 									Items: []corev1.KeyToPath{{Key: "index.html", Path: "index.html"}},
 								},
@@ -108,7 +137,7 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 							VolumeSource: corev1.VolumeSource{
 								ConfigMap: &corev1.ConfigMapVolumeSource{
 									LocalObjectReference: corev1.LocalObjectReference{
-										Name: "k8s-function-checker-cm",
+										Name: objectName(configMapName),
 									},
 									Items: []corev1.KeyToPath{{Key: "service-checker.sh", Path: "service-checker.sh",
 										Mode: int32Ptr(0755)}},
@@ -126,8 +155,7 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 			Name: "pvc",
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-			StorageClassName: &scName,
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceStorage: storageRequest,
@@ -135,6 +163,10 @@ func NewStatefulSet(namespace, scName string, storageRequest resource.Quantity)
 			},
 		},
 	}}
+	// Without a storageclass the claims are provisioned by the default one of the cluster.
+	if scName != "" {
+		s.sts.Spec.VolumeClaimTemplates[0].Spec.StorageClassName = &scName
+	}
 
 	return s
 }
@@ -147,8 +179,44 @@ func (s *StatefulSet) FormatedName() string {
 	return strings.Join([]string{s.sts.Namespace, "statefulsets", s.sts.Name}, "/")
 }
 
-func (s *StatefulSet) Create(client *kubernetes.Clientset) error {
-	_, err := client.AppsV1().StatefulSets(s.sts.Namespace).Create(context.Background(), s.sts, metav1.CreateOptions{})
+func (s *StatefulSet) Object() runtime.Object {
+	return s.sts
+}
+
+// PodNames returns the names of all replicas of the statefulset.
+func (s *StatefulSet) PodNames() []string {
+	var names []string
+	for n := int32(0); n < *s.sts.Spec.Replicas; n++ {
+		names = append(names, fmt.Sprintf("%s-%d", s.sts.Name, n))
+	}
+	return names
+}
+
+// ClaimName returns the name of the persistentvolumeclaim mounted by the replica podName.
+func (s *StatefulSet) ClaimName(podName string) string {
+	return strings.Join([]string{s.sts.Spec.VolumeClaimTemplates[0].Name, podName}, "-")
+}
+
+// ClaimNames returns the names of the persistentvolumeclaims of all replicas.
+func (s *StatefulSet) ClaimNames() []string {
+	var names []string
+	for _, podName := range s.PodNames() {
+		names = append(names, s.ClaimName(podName))
+	}
+	return names
+}
+
+func (s *StatefulSet) Namespace() string {
+	return s.sts.Namespace
+}
+
+// ServiceName returns the name of the service governing the statefulset.
+func (s *StatefulSet) ServiceName() string {
+	return s.sts.Spec.ServiceName
+}
+
+func (s *StatefulSet) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.AppsV1().StatefulSets(s.sts.Namespace).Create(ctx, s.sts, metav1.CreateOptions{DryRun: DryRun})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -162,17 +230,16 @@ func (s *StatefulSet) IsCreated() bool {
 	return s.created
 }
 
-func (s *StatefulSet) Delete(client *kubernetes.Clientset) error {
-	err := client.AppsV1().StatefulSets(s.sts.Namespace).Delete(context.Background(), s.sts.Name, metav1.DeleteOptions{})
+func (s *StatefulSet) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.AppsV1().StatefulSets(s.sts.Namespace).Delete(ctx, s.sts.Name, metav1.DeleteOptions{})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
 	}
 
-	err = client.CoreV1().PersistentVolumeClaims(s.sts.Namespace).DeleteCollection(context.Background(), metav1.DeleteOptions{}, metav1.ListOptions{
-		LabelSelector: labels.FormatLabels(map[string]string{
-			"component": "k8s-function-checker",
-		}),
+	// Claims are labeled with the selector of the statefulset, which is scoped to the run.
+	err = client.CoreV1().PersistentVolumeClaims(s.sts.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: labels.FormatLabels(s.sts.Spec.Selector.MatchLabels),
 	})
 	if err != nil {
 		klog.Infoln(err.Error())
@@ -182,8 +249,8 @@ func (s *StatefulSet) Delete(client *kubernetes.Clientset) error {
 	return nil
 }
 
-func (s *StatefulSet) IsExist(client *kubernetes.Clientset) bool {
-	_, err := client.AppsV1().StatefulSets(s.sts.Namespace).Get(context.Background(), s.sts.Name, metav1.GetOptions{})
+func (s *StatefulSet) IsExist(ctx context.Context, client kubernetes.Interface) bool {
+	_, err := client.AppsV1().StatefulSets(s.sts.Namespace).Get(ctx, s.sts.Name, metav1.GetOptions{})
 	if err != nil {
 		if !apierrors.IsNotFound(err) {
 			return true
@@ -193,79 +260,146 @@ func (s *StatefulSet) IsExist(client *kubernetes.Clientset) bool {
 	return false
 }
 
-func (s *StatefulSet) WaitForReady(client *kubernetes.Clientset) {
-	klog.Infof("Waiting for [%s/statefulsets/%s] to become ready", s.sts.Namespace, s.sts.Name)
+// WaitForReady watches the statefulset until all replicas are ready. On timeout the
+// returned error explains which pods are not ready and why.
+func (s *StatefulSet) WaitForReady(ctx context.Context, client kubernetes.Interface, timeout time.Duration) error {
+	klog.Infof("Waiting for [%s] to become ready", s.FormatedName())
 	startTime := time.Now()
 
-	timeCh := time.After(waitTimeout)
-	retryTicker := time.NewTicker(waitTicker)
-	defer retryTicker.Stop()
-
-loop:
-	for {
-		select {
-		case <-timeCh:
-			klog.Errorln("Waiting timeout")
-		case <-retryTicker.C:
-			sts, err := client.AppsV1().StatefulSets(s.sts.Namespace).Get(context.Background(), s.sts.Name, metav1.GetOptions{})
-			if err != nil {
-				continue
-			}
-			if *sts.Spec.Replicas == sts.Status.ReadyReplicas {
-				klog.Infof("Waiting for [%s] before status become ready", time.Since(startTime).String())
-				break loop
-			}
-		default:
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", s.sts.Name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.AppsV1().StatefulSets(s.sts.Namespace).List(waitCtx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.AppsV1().StatefulSets(s.sts.Namespace).Watch(waitCtx, options)
+		},
+	}
+
+	_, err := watchtools.UntilWithSync(waitCtx, lw, &appsv1.StatefulSet{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("[%s] was deleted", s.FormatedName())
+		}
+		sts, ok := event.Object.(*appsv1.StatefulSet)
+		if !ok {
+			return false, nil
 		}
+		return sts.Status.ObservedGeneration >= sts.Generation &&
+			sts.Status.ReadyReplicas == *sts.Spec.Replicas, nil
+	})
+	if err == nil {
+		klog.Infof("Waiting for [%s] before status become ready", time.Since(startTime).String())
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != wait.ErrWaitTimeout {
+		return err
 	}
-}
 
-func (s *StatefulSet) AccessFromInternal(client *kubernetes.Clientset, rc *rest.Config) bool {
-	podName := strings.Join([]string{s.sts.Name, "0"}, "-")
+	notReady, derr := describeNotReadyPods(ctx, client, s.sts.Namespace,
+		labels.FormatLabels(s.sts.Spec.Selector.MatchLabels), s.PodNames())
+	if derr != nil {
+		return fmt.Errorf("[%s] not ready after %s: %v", s.FormatedName(), timeout, derr)
+	}
+	return fmt.Errorf("[%s] not ready after %s: %s", s.FormatedName(), timeout, strings.Join(notReady, ", "))
+}
 
-	execCommandName := "/bin/bash /script/service-checker.sh"
-	execCommand := fmt.Sprintf("%s %d %s", execCommandName, 3,
-		fmt.Sprintf("%s.%s", s.sts.Spec.ServiceName, s.sts.Namespace))
-	klog.Infof("Test access from pod [%s] to service [k8s-function-checker-svc],use command [%s]", podName, execCommand)
-
-	req := client.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(podName).
-		Namespace(s.sts.Namespace).
-		SubResource("exec").
-		MaxRetries(3).
-		VersionedParams(&corev1.PodExecOptions{
-			Container: "function-check-container",
-			Command:   strings.Fields(execCommand),
-			Stdin:     false,
-			Stdout:    true,
-			Stderr:    true,
-			TTY:       false,
-		}, scheme.ParameterCodec)
-
-	klog.V(5).Infof("req.URL()=%s", req.URL().String())
-	exec, err := remotecommand.NewSPDYExecutor(rc, "POST", req.URL())
+// RecreatePod deletes the replica podName and waits until the statefulset controller
+// brought up a new ready pod on the same persistentvolumeclaim.
+func (s *StatefulSet) RecreatePod(ctx context.Context, client kubernetes.Interface, podName string,
+	timeout time.Duration) (*corev1.Pod, error) {
+	old, err := client.CoreV1().Pods(s.sts.Namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
-		return false
+		return nil, err
 	}
 
-	var stdout, stderr bytes.Buffer
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: &stdout,
-		Stderr: &stderr,
-		Tty:    false,
+	klog.Infof("Delete pod [%s/pods/%s] and wait for it to be recreated", s.sts.Namespace, podName)
+	if err := client.CoreV1().Pods(s.sts.Namespace).Delete(ctx, podName, metav1.DeleteOptions{}); err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", podName).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(s.sts.Namespace).List(waitCtx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(s.sts.Namespace).Watch(waitCtx, options)
+		},
+	}
+
+	var recreated *corev1.Pod
+	_, err = watchtools.UntilWithSync(waitCtx, lw, &corev1.Pod{}, nil, func(event watch.Event) (bool, error) {
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok || event.Type == watch.Deleted {
+			return false, nil
+		}
+		if pod.UID == old.UID || !IsPodReady(pod) {
+			return false, nil
+		}
+		recreated = pod
+		return true, nil
 	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != wait.ErrWaitTimeout {
+			return nil, err
+		}
+		notReady, derr := describeNotReadyPods(ctx, client, s.sts.Namespace,
+			labels.FormatLabels(s.sts.Spec.Selector.MatchLabels), []string{podName})
+		if derr != nil || len(notReady) == 0 {
+			return nil, fmt.Errorf("pod [%s] not recreated after %s", podName, timeout)
+		}
+		return nil, fmt.Errorf("pod [%s] not recreated after %s: %s", podName, timeout, strings.Join(notReady, ", "))
+	}
+
+	claimName := s.ClaimName(podName)
+	for _, vol := range recreated.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == claimName {
+			return recreated, nil
+		}
+	}
+
+	return recreated, fmt.Errorf("recreated pod [%s] does not mount persistentvolumeclaim [%s]", podName, claimName)
+}
 
+// Exec runs command in the nginx container of the replica podName.
+func (s *StatefulSet) Exec(ctx context.Context, client kubernetes.Interface, rc *rest.Config,
+	podName string, command []string) (string, string, error) {
+	return Exec(ctx, client, rc, s.sts.Namespace, podName, ContainerName, command)
+}
+
+// AccessFromInternal curls host from the first replica and returns the output of the command.
+func (s *StatefulSet) AccessFromInternal(ctx context.Context, client kubernetes.Interface, rc *rest.Config,
+	host string) (string, error) {
+	podName := strings.Join([]string{s.sts.Name, "0"}, "-")
+
+	execCommandName := "/bin/bash /script/service-checker.sh"
+	execCommand := fmt.Sprintf("%s %d %s", execCommandName, 3, host)
+	klog.Infof("Test access from pod [%s] to [%s],use command [%s]", podName, host, execCommand)
+
+	stdout, stderr, err := s.Exec(ctx, client, rc, podName, strings.Fields(execCommand))
 	if err != nil {
-		klog.Infof("Error occur when execute command in the pod,err=%s", err.Error())
-		klog.Infof("Exec stdout=%v", stdout)
-		klog.Infof("Exec stderr=%v", stderr)
-		return false
+		return stdout + stderr, err
 	}
 
-	if strings.EqualFold(stdout.String(), strings.Repeat("it works!", 3)) {
-		return true
+	if !strings.EqualFold(stdout, strings.Repeat(Page, 3)) {
+		return stdout, fmt.Errorf("unexpected output from [%s]", host)
 	}
 
-	return false
+	return stdout, nil
 }
@@ -0,0 +1,179 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestStatefulSet() *StatefulSet {
+	return NewStatefulSet(testNamespace, "standard", apiresource.MustParse("1Gi"))
+}
+
+func TestStatefulSetNames(t *testing.T) {
+	s := newTestStatefulSet()
+
+	want := []string{"k8s-function-checker-sts-abc123-0", "k8s-function-checker-sts-abc123-1", "k8s-function-checker-sts-abc123-2"}
+	got := s.PodNames()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("PodNames() = %v, want %v", got, want)
+	}
+	if got := s.ClaimName(want[0]); got != "pvc-k8s-function-checker-sts-abc123-0" {
+		t.Errorf("ClaimName() = %q", got)
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	s := newTestStatefulSet()
+	ready := s.sts.DeepCopy()
+	ready.Status.ReadyReplicas = *ready.Spec.Replicas
+
+	client := fake.NewSimpleClientset(ready)
+	if err := s.WaitForReady(context.Background(), client, 5*time.Second); err != nil {
+		t.Errorf("WaitForReady() error = %v", err)
+	}
+}
+
+func TestWaitForReadyTimeout(t *testing.T) {
+	s := newTestStatefulSet()
+	notReady := s.sts.DeepCopy()
+	notReady.Status.ReadyReplicas = 1
+
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Labels: runLabels(stsLabels)},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name: "pvc",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: s.ClaimName(name),
+				}},
+			}}},
+		}
+	}
+
+	readyPod := pod("k8s-function-checker-sts-abc123-0")
+	readyPod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+
+	unschedulable := pod("k8s-function-checker-sts-abc123-1")
+	unschedulable.Status.Phase = corev1.PodPending
+	unschedulable.Status.Conditions = []corev1.PodCondition{{
+		Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Message: "0/3 nodes are available",
+	}}
+	unbound := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: s.ClaimName(unschedulable.Name), Namespace: testNamespace},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+
+	client := fake.NewSimpleClientset(notReady, readyPod, unschedulable, unbound)
+	err := s.WaitForReady(context.Background(), client, 100*time.Millisecond)
+	if err == nil {
+		t.Fatalf("WaitForReady() error = nil, want timeout")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"pod [k8s-function-checker-sts-abc123-1]: pending scheduling: 0/3 nodes are available",
+		"unbound persistentvolumeclaim [pvc-k8s-function-checker-sts-abc123-1] is Pending",
+		"pod [k8s-function-checker-sts-abc123-2]: not created",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("WaitForReady() error = %q, want it to contain %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "k8s-function-checker-sts-abc123-0") {
+		t.Errorf("WaitForReady() error = %q, ready pod should not be reported", msg)
+	}
+}
+
+func TestWaitForReadyCancelled(t *testing.T) {
+	s := newTestStatefulSet()
+	client := fake.NewSimpleClientset(s.sts.DeepCopy())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.WaitForReady(ctx, client, time.Minute); err != context.Canceled {
+		t.Errorf("WaitForReady() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRecreatePod(t *testing.T) {
+	s := newTestStatefulSet()
+	podName := "k8s-function-checker-sts-abc123-0"
+	pod := func(uid string, claim string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: testNamespace, Labels: runLabels(stsLabels), UID: types.UID(uid)},
+			Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				Name: "pvc",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claim,
+				}},
+			}}},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		claim   string
+		wantErr bool
+	}{
+		{name: "same claim", claim: s.ClaimName(podName)},
+		{name: "other claim", claim: "pvc-other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(pod("old", s.ClaimName(podName)))
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				client.CoreV1().Pods(testNamespace).Create(context.Background(), pod("new", tt.claim), metav1.CreateOptions{})
+			}()
+
+			got, err := s.RecreatePod(context.Background(), client, podName, 5*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecreatePod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got == nil || got.UID != "new" {
+				t.Errorf("RecreatePod() = %v, want the recreated pod", got)
+			}
+		})
+	}
+}
+
+func TestStatefulSetDeleteScopedToRun(t *testing.T) {
+	s := newTestStatefulSet()
+
+	RunID = "other1"
+	other := newTestStatefulSet()
+	RunID = testRunID
+
+	if s.sts.Name == other.sts.Name {
+		t.Fatalf("statefulsets of two runs are both named [%s]", s.sts.Name)
+	}
+
+	client := fake.NewSimpleClientset(s.sts.DeepCopy(), other.sts.DeepCopy())
+	var selector string
+	client.PrependReactor("delete-collection", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selector = action.(k8stesting.DeleteCollectionAction).GetListRestrictions().Labels.String()
+		return true, nil, nil
+	})
+
+	if err := s.Delete(context.Background(), client); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if !strings.Contains(selector, RunIDLabel+"="+testRunID) {
+		t.Errorf("persistentvolumeclaims deleted by selector %q, want it scoped to run %s", selector, testRunID)
+	}
+	if _, err := client.AppsV1().StatefulSets(testNamespace).Get(context.Background(), other.sts.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("statefulset of the other run was deleted: %v", err)
+	}
+}
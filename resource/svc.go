@@ -2,15 +2,35 @@ package resource
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
+const (
+	// headlessServiceName is the name of the headless service governing the statefulset,
+	// before the RunID is suffixed.
+	headlessServiceName = "k8s-function-checker-headless"
+	// serviceName is the name of the ClusterIP service, which is the ingress backend,
+	// before the RunID is suffixed.
+	serviceName = "k8s-function-checker-svc"
+	// nodePortServiceName, loadBalancerServiceName and externalNameServiceName are the names
+	// of the services probed by the service checks, before the RunID is suffixed.
+	nodePortServiceName     = "k8s-function-checker-nodeport"
+	loadBalancerServiceName = "k8s-function-checker-lb"
+	externalNameServiceName = "k8s-function-checker-externalname"
+)
+
 var (
 	svcLabels = map[string]string{
 		"kind":      "service",
@@ -25,19 +45,18 @@ type Service struct {
 	created bool
 }
 
-func NewService(namespace string) *Service {
+func newService(namespace, name string, svcType corev1.ServiceType) *Service {
 	s := new(Service)
 
 	s.svc = &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "k8s-function-checker-svc",
+			Name:      objectName(name),
 			Namespace: namespace,
-			Labels:    svcLabels,
+			Labels:    runLabels(svcLabels),
 		},
 		Spec: corev1.ServiceSpec{
-			Selector: map[string]string{
-				"component": "k8s-function-checker",
-			},
+			Type:     svcType,
+			Selector: runLabels(stsLabels),
 			Ports: []corev1.ServicePort{{
 				Protocol:   corev1.ProtocolTCP,
 				Port:       80,
@@ -49,12 +68,60 @@ func NewService(namespace string) *Service {
 	return s
 }
 
+// NewService builds the ClusterIP service in front of the statefulset.
+func NewService(namespace string) *Service {
+	return newService(namespace, serviceName, corev1.ServiceTypeClusterIP)
+}
+
+// NewHeadlessService builds the headless service governing the statefulset, which gives
+// every replica its own DNS record.
+func NewHeadlessService(namespace string) *Service {
+	s := newService(namespace, headlessServiceName, corev1.ServiceTypeClusterIP)
+	s.svc.Spec.ClusterIP = corev1.ClusterIPNone
+
+	return s
+}
+
+// NewNodePortService builds a NodePort service in front of the statefulset, the node port
+// is allocated by the cluster.
+func NewNodePortService(namespace string) *Service {
+	return newService(namespace, nodePortServiceName, corev1.ServiceTypeNodePort)
+}
+
+// NewLoadBalancerService builds a LoadBalancer service in front of the statefulset.
+func NewLoadBalancerService(namespace string) *Service {
+	return newService(namespace, loadBalancerServiceName, corev1.ServiceTypeLoadBalancer)
+}
+
+// NewExternalNameService builds an ExternalName service aliasing externalName.
+func NewExternalNameService(namespace, externalName string) *Service {
+	s := newService(namespace, externalNameServiceName, corev1.ServiceTypeExternalName)
+	s.svc.Spec.ExternalName = externalName
+	s.svc.Spec.Selector = nil
+	s.svc.Spec.Ports = nil
+
+	return s
+}
+
 func (s *Service) FormatedName() string {
 	return strings.Join([]string{s.svc.Namespace, "services", s.svc.Name}, "/")
 }
 
-func (s *Service) Create(client *kubernetes.Clientset) error {
-	_, err := client.CoreV1().Services(s.svc.Namespace).Create(context.Background(), s.svc, metav1.CreateOptions{})
+func (s *Service) Object() runtime.Object {
+	return s.svc
+}
+
+func (s *Service) Name() string {
+	return s.svc.Name
+}
+
+// ExternalName returns the name aliased by an ExternalName service.
+func (s *Service) ExternalName() string {
+	return s.svc.Spec.ExternalName
+}
+
+func (s *Service) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.CoreV1().Services(s.svc.Namespace).Create(ctx, s.svc, metav1.CreateOptions{DryRun: DryRun})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -68,8 +135,8 @@ func (s *Service) IsCreated() bool {
 	return s.created
 }
 
-func (s *Service) Delete(client *kubernetes.Clientset) error {
-	err := client.CoreV1().Services(s.svc.Namespace).Delete(context.Background(), s.svc.Name, metav1.DeleteOptions{})
+func (s *Service) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().Services(s.svc.Namespace).Delete(ctx, s.svc.Name, metav1.DeleteOptions{})
 	if err != nil {
 		klog.Infoln(err.Error())
 		return err
@@ -77,3 +144,84 @@ func (s *Service) Delete(client *kubernetes.Clientset) error {
 
 	return nil
 }
+
+// Get returns the service as stored in the cluster, with the fields allocated on creation.
+func (s *Service) Get(ctx context.Context, client kubernetes.Interface) (*corev1.Service, error) {
+	return client.CoreV1().Services(s.svc.Namespace).Get(ctx, s.svc.Name, metav1.GetOptions{})
+}
+
+// WaitForLoadBalancer waits until the cloud provider assigns an address to the service and
+// returns it as host:port. It returns an empty address without error if none was assigned
+// within timeout, which usually means the cluster has no load balancer provider.
+func (s *Service) WaitForLoadBalancer(ctx context.Context, client kubernetes.Interface, timeout time.Duration) (string, error) {
+	klog.Infof("Waiting for load balancer address of [%s]", s.FormatedName())
+
+	timeCh := time.After(timeout)
+	retryTicker := time.NewTicker(waitTicker)
+	defer retryTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeCh:
+			return "", nil
+		case <-retryTicker.C:
+			svc, err := s.Get(ctx, client)
+			if err != nil {
+				klog.Infoln(err.Error())
+				continue
+			}
+			port := strconv.Itoa(int(s.svc.Spec.Ports[0].Port))
+			for _, lb := range svc.Status.LoadBalancer.Ingress {
+				if lb.IP != "" {
+					return net.JoinHostPort(lb.IP, port), nil
+				}
+				if lb.Hostname != "" {
+					return net.JoinHostPort(lb.Hostname, port), nil
+				}
+			}
+		}
+	}
+}
+
+// VerifyEndpoints checks that the endpoints of the service list every pod in pods by its
+// IP, with the pod name as hostname as a headless service governing a statefulset does.
+func (s *Service) VerifyEndpoints(ctx context.Context, client kubernetes.Interface, pods []*corev1.Pod) error {
+	ep, err := client.CoreV1().Endpoints(s.svc.Namespace).Get(ctx, s.svc.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	byIP := map[string]corev1.EndpointAddress{}
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			byIP[addr.IP] = addr
+		}
+	}
+
+	var missing []string
+	for _, pod := range pods {
+		addr, ok := byIP[pod.Status.PodIP]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("pod [%s] with ip %s has no endpoint", pod.Name, pod.Status.PodIP))
+		case addr.Hostname != pod.Name:
+			missing = append(missing, fmt.Sprintf("endpoint %s has hostname [%s], want [%s]", addr.IP, addr.Hostname, pod.Name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("endpoints of [%s]: %s", s.FormatedName(), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// AccessFromExternal requests the page served by the statefulset through address from
+// outside the cluster and returns the last response body.
+func (s *Service) AccessFromExternal(ctx context.Context, address string) (string, error) {
+	url := fmt.Sprintf("http://%s/", address)
+	klog.Infof("Test access to service [%s] through [%s]", s.FormatedName(), url)
+
+	return accessPage(ctx, &http.Client{Timeout: httpProbeTimeout}, url, "")
+}
@@ -0,0 +1,96 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceTypes(t *testing.T) {
+	tests := []struct {
+		svc       *Service
+		wantName  string
+		wantType  corev1.ServiceType
+		clusterIP string
+	}{
+		{svc: NewService(testNamespace), wantName: "k8s-function-checker-svc-abc123", wantType: corev1.ServiceTypeClusterIP},
+		{svc: NewHeadlessService(testNamespace), wantName: "k8s-function-checker-headless-abc123", wantType: corev1.ServiceTypeClusterIP, clusterIP: corev1.ClusterIPNone},
+		{svc: NewNodePortService(testNamespace), wantName: "k8s-function-checker-nodeport-abc123", wantType: corev1.ServiceTypeNodePort},
+		{svc: NewLoadBalancerService(testNamespace), wantName: "k8s-function-checker-lb-abc123", wantType: corev1.ServiceTypeLoadBalancer},
+		{svc: NewExternalNameService(testNamespace, "example.com"), wantName: "k8s-function-checker-externalname-abc123", wantType: corev1.ServiceTypeExternalName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.svc.Name(), func(t *testing.T) {
+			if tt.svc.Name() != tt.wantName {
+				t.Errorf("name = %s, want %s", tt.svc.Name(), tt.wantName)
+			}
+			if tt.svc.svc.Spec.Type != tt.wantType {
+				t.Errorf("type = %s, want %s", tt.svc.svc.Spec.Type, tt.wantType)
+			}
+			if tt.svc.svc.Spec.ClusterIP != tt.clusterIP {
+				t.Errorf("clusterIP = %q, want %q", tt.svc.svc.Spec.ClusterIP, tt.clusterIP)
+			}
+		})
+	}
+
+	if got, want := newTestStatefulSet().ServiceName(), NewHeadlessService(testNamespace).Name(); got != want {
+		t.Errorf("statefulset is governed by [%s], want [%s]", got, want)
+	}
+}
+
+func TestWaitForLoadBalancer(t *testing.T) {
+	s := NewLoadBalancerService(testNamespace)
+
+	pending := s.svc.DeepCopy()
+	client := fake.NewSimpleClientset(pending)
+	address, err := s.WaitForLoadBalancer(context.Background(), client, 100*time.Millisecond)
+	if err != nil || address != "" {
+		t.Errorf("WaitForLoadBalancer() = %q, %v, want no address", address, err)
+	}
+
+	assigned := s.svc.DeepCopy()
+	assigned.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}}
+	client = fake.NewSimpleClientset(assigned)
+	address, err = s.WaitForLoadBalancer(context.Background(), client, 5*time.Second)
+	if err != nil || address != "192.0.2.10:80" {
+		t.Errorf("WaitForLoadBalancer() = %q, %v, want 192.0.2.10:80", address, err)
+	}
+}
+
+func TestVerifyEndpoints(t *testing.T) {
+	s := NewHeadlessService(testNamespace)
+	pod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	pods := []*corev1.Pod{
+		pod("k8s-function-checker-sts-0", "10.0.0.1"),
+		pod("k8s-function-checker-sts-1", "10.0.0.2"),
+		pod("k8s-function-checker-sts-2", "10.0.0.3"),
+	}
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name(), Namespace: testNamespace},
+		Subsets: []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{
+			{IP: "10.0.0.1", Hostname: "k8s-function-checker-sts-0"},
+			{IP: "10.0.0.2", Hostname: "k8s-function-checker-sts-1"},
+		}}},
+	}
+
+	client := fake.NewSimpleClientset(ep)
+	if err := s.VerifyEndpoints(context.Background(), client, pods[:2]); err != nil {
+		t.Errorf("VerifyEndpoints() error = %v", err)
+	}
+
+	err := s.VerifyEndpoints(context.Background(), client, pods)
+	if err == nil || !strings.Contains(err.Error(), "pod [k8s-function-checker-sts-2] with ip 10.0.0.3 has no endpoint") {
+		t.Errorf("VerifyEndpoints() error = %v, want missing endpoint of k8s-function-checker-sts-2", err)
+	}
+}
@@ -0,0 +1,159 @@
+package resource
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// certValidity is how long the generated certificates are valid, they only live for one run.
+	certValidity = time.Duration(24) * time.Hour
+)
+
+var (
+	secretLabels = map[string]string{
+		"kind":      "secret",
+		"component": "k8s-function-checker",
+	}
+)
+
+var _ OperatorInterface = &TLSSecret{}
+
+// TLSSecret is a kubernetes.io/tls secret holding a server certificate for the ingress host,
+// signed by a throwaway CA generated along with it.
+type TLSSecret struct {
+	secret  *corev1.Secret
+	ca      *x509.Certificate
+	created bool
+}
+
+// NewTLSSecret generates a CA and a server certificate for host signed by it.
+func NewTLSSecret(namespace, host string) (*TLSSecret, error) {
+	ca, caKey, err := newCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "k8s-function-checker-ca"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	cert, key, err := newCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: host},
+		DNSNames:    []string{host},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(TLSSecret)
+	s.ca = ca
+	s.secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      objectName("k8s-function-checker-tls"),
+			Namespace: namespace,
+			Labels:    runLabels(secretLabels),
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+
+	return s, nil
+}
+
+// newCertificate signs template with parent and parentKey, or self-signs it if parent is nil.
+func newCertificate(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(certValidity)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+func (s *TLSSecret) FormatedName() string {
+	return strings.Join([]string{s.secret.Namespace, "secrets", s.secret.Name}, "/")
+}
+
+func (s *TLSSecret) Object() runtime.Object {
+	return s.secret
+}
+
+func (s *TLSSecret) Name() string {
+	return s.secret.Name
+}
+
+// CAPool returns a pool holding only the generated CA.
+func (s *TLSSecret) CAPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.ca)
+	return pool
+}
+
+func (s *TLSSecret) Create(ctx context.Context, client kubernetes.Interface) error {
+	_, err := client.CoreV1().Secrets(s.secret.Namespace).Create(ctx, s.secret, metav1.CreateOptions{DryRun: DryRun})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+	s.created = true
+
+	return nil
+}
+
+func (s *TLSSecret) IsCreated() bool {
+	return s.created
+}
+
+func (s *TLSSecret) Delete(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().Secrets(s.secret.Namespace).Delete(ctx, s.secret.Name, metav1.DeleteOptions{})
+	if err != nil {
+		klog.Infoln(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package resource
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newTestTLSServer(t *testing.T, secret *TLSSecret) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Page)
+	}))
+	if secret != nil {
+		cert, err := tls.X509KeyPair(secret.secret.Data[corev1.TLSCertKey], secret.secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			t.Fatalf("X509KeyPair() error = %v", err)
+		}
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestAccessFromExternalTLS(t *testing.T) {
+	ing := NewIngress(testNamespace, "nginx", "nginx", "nginx-test.example.com")
+	if ing.Host() != "abc123.nginx-test.example.com" {
+		t.Errorf("host = %s, want the run ID under the domain", ing.Host())
+	}
+	secret, err := NewTLSSecret(testNamespace, ing.Host())
+	if err != nil {
+		t.Fatalf("NewTLSSecret() error = %v", err)
+	}
+	if secret.secret.Type != corev1.SecretTypeTLS {
+		t.Errorf("secret type = %s, want %s", secret.secret.Type, corev1.SecretTypeTLS)
+	}
+
+	ing.EnableTLS(secret)
+	if len(ing.ing.Spec.TLS) != 1 || ing.ing.Spec.TLS[0].SecretName != secret.Name() {
+		t.Fatalf("ingress tls = %+v, want the generated secret", ing.ing.Spec.TLS)
+	}
+	if hosts := ing.ing.Spec.TLS[0].Hosts; len(hosts) != 1 || hosts[0] != ing.Host() {
+		t.Errorf("tls hosts = %v, want [%s]", hosts, ing.Host())
+	}
+
+	server := newTestTLSServer(t, secret)
+	served, body, err := ing.AccessFromExternalTLS(context.Background(), server.Listener.Addr().String(), secret.CAPool())
+	if err != nil {
+		t.Fatalf("AccessFromExternalTLS() error = %v", err)
+	}
+	if body != Page {
+		t.Errorf("body = %q, want %q", body, Page)
+	}
+	if !strings.Contains(served, "issuer=CN=k8s-function-checker-ca") {
+		t.Errorf("served = %q, want it issued by the generated CA", served)
+	}
+}
+
+func TestVerifyServedCertificateUnknownAuthority(t *testing.T) {
+	secret, err := NewTLSSecret(testNamespace, "nginx-test.example.com")
+	if err != nil {
+		t.Fatalf("NewTLSSecret() error = %v", err)
+	}
+
+	// The default certificate of httptest stands in for the fake certificate of the controller.
+	server := newTestTLSServer(t, nil)
+	_, err = verifyServedCertificate(context.Background(), server.Listener.Addr().String(),
+		"nginx-test.example.com", secret.CAPool())
+	if err == nil || !strings.Contains(err.Error(), "is not the generated one") {
+		t.Errorf("verifyServedCertificate() error = %v, want verification failure", err)
+	}
+}
@@ -0,0 +1,172 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tiggoins/function-checker/report"
+	"k8s.io/klog/v2"
+)
+
+const namespace = "function_checker"
+
+// Server exposes the results of periodic runs as Prometheus metrics and as the latest report.
+type Server struct {
+	registry *prometheus.Registry
+
+	checkSuccess  *prometheus.GaugeVec
+	checkSkipped  *prometheus.GaugeVec
+	checkDuration *prometheus.HistogramVec
+	checkLastRun  *prometheus.GaugeVec
+	runSuccess    prometheus.Gauge
+	runDuration   prometheus.Histogram
+	runLastRun    prometheus.Gauge
+	runsTotal     *prometheus.CounterVec
+
+	mu     sync.RWMutex
+	latest *report.Report
+}
+
+func New() *Server {
+	s := &Server{registry: prometheus.NewRegistry()}
+	labels := []string{"phase", "check"}
+	buckets := []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+	s.checkSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "check_success",
+		Help:      "Whether the check passed (1) or failed (0) in the latest run, absent if it was skipped.",
+	}, labels)
+	s.checkSkipped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "check_skipped",
+		Help:      "Whether the check was skipped in the latest run.",
+	}, labels)
+	s.checkDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "check_duration_seconds",
+		Help:      "Duration of the check.",
+		Buckets:   buckets,
+	}, labels)
+	s.checkLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "check_last_run_timestamp_seconds",
+		Help:      "Unix time the latest run of the check finished.",
+	}, labels)
+	s.runSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "run_success",
+		Help:      "Whether every check of the latest run passed or was skipped.",
+	})
+	s.runDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of a run, from connecting to the cluster to cleanup.",
+		Buckets:   buckets,
+	})
+	s.runLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "run_last_run_timestamp_seconds",
+		Help:      "Unix time the latest run finished.",
+	})
+	s.runsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Number of runs by result.",
+	}, []string{"result"})
+
+	s.registry.MustRegister(s.checkSuccess, s.checkSkipped, s.checkDuration, s.checkLastRun,
+		s.runSuccess, s.runDuration, s.runLastRun, s.runsTotal)
+
+	return s
+}
+
+// Record updates the metrics with a finished run and keeps rp as the latest report.
+func (s *Server) Record(rp *report.Report) {
+	finished := rp.StartTime.Add(rp.Duration.Duration)
+
+	// Forget results of the previous run, a check may not have run this time.
+	s.checkSuccess.Reset()
+	s.checkSkipped.Reset()
+	for _, res := range rp.Results {
+		labels := prometheus.Labels{"phase": string(res.Phase), "check": res.Name}
+		switch res.Status {
+		case report.StatusPass:
+			s.checkSuccess.With(labels).Set(1)
+			s.checkSkipped.With(labels).Set(0)
+		case report.StatusFail:
+			s.checkSuccess.With(labels).Set(0)
+			s.checkSkipped.With(labels).Set(0)
+		case report.StatusSkip:
+			s.checkSkipped.With(labels).Set(1)
+			continue
+		}
+		s.checkDuration.With(labels).Observe(res.Duration.Seconds())
+		s.checkLastRun.With(labels).Set(float64(finished.Unix()))
+	}
+
+	result := "success"
+	if rp.Failed() {
+		result = "failure"
+		s.runSuccess.Set(0)
+	} else {
+		s.runSuccess.Set(1)
+	}
+	s.runsTotal.WithLabelValues(result).Inc()
+	s.runDuration.Observe(rp.Duration.Seconds())
+	s.runLastRun.Set(float64(finished.Unix()))
+
+	s.mu.Lock()
+	s.latest = rp
+	s.mu.Unlock()
+}
+
+// Handler serves /metrics, /healthz and /results, the latter returning the latest report as JSON.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/results", s.serveResults)
+
+	return mux
+}
+
+func (s *Server) serveResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	latest := s.latest
+	s.mu.RUnlock()
+
+	if latest == nil {
+		http.Error(w, "no run finished yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := latest.Write(w, report.FormatJSON); err != nil {
+		klog.Warningf("Error happened when write report: %s", err.Error())
+	}
+}
+
+// Loop calls run every interval, starting immediately, and records its report until stop is
+// closed. stop is only looked at between runs, a run in progress is not interrupted by it and
+// Loop returns once that run finished, so run has to cancel itself to stop sooner.
+func (s *Server) Loop(interval time.Duration, stop <-chan struct{}, run func() *report.Report) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Record(run())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
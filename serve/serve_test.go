@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tiggoins/function-checker/report"
+)
+
+func newTestReport() *report.Report {
+	rp := report.New()
+	rp.Run(report.PhaseSetup, "connect cluster", func(*report.Result) error { return nil })
+	rp.Run(report.PhaseCheck, "dns", func(*report.Result) error { return errors.New("cannot resolve") })
+	rp.Run(report.PhaseCheck, "ingress", func(res *report.Result) error {
+		res.Skipf("ingress resource was not created")
+		return nil
+	})
+	rp.Finish()
+
+	return rp
+}
+
+func get(t *testing.T, server *httptest.Server, path string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s error = %v", path, err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestHandler(t *testing.T) {
+	s := New()
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	if code, _ := get(t, server, "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want %d", code, http.StatusOK)
+	}
+	if code, _ := get(t, server, "/results"); code != http.StatusServiceUnavailable {
+		t.Errorf("/results status = %d before any run, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	s.Record(newTestReport())
+
+	code, body := get(t, server, "/results")
+	if code != http.StatusOK || !strings.Contains(body, `"failed": 1`) {
+		t.Errorf("/results = %d %s, want the latest report", code, body)
+	}
+
+	_, metrics := get(t, server, "/metrics")
+	for _, want := range []string{
+		`function_checker_check_success{check="connect cluster",phase="setup"} 1`,
+		`function_checker_check_success{check="dns",phase="check"} 0`,
+		`function_checker_check_skipped{check="ingress",phase="check"} 1`,
+		`function_checker_check_duration_seconds_count{check="dns",phase="check"} 1`,
+		`function_checker_run_success 0`,
+		`function_checker_runs_total{result="failure"} 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("/metrics does not contain %q", want)
+		}
+	}
+	if strings.Contains(metrics, `function_checker_check_success{check="ingress"`) {
+		t.Errorf("/metrics should not report success of a skipped check")
+	}
+}
+
+func TestLoop(t *testing.T) {
+	s := New()
+	stop := make(chan struct{})
+	runs := 0
+
+	done := make(chan struct{})
+	go func() {
+		s.Loop(10*time.Millisecond, stop, func() *report.Report {
+			runs++
+			if runs == 3 {
+				close(stop)
+			}
+			return newTestReport()
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Loop() did not return after stop was closed")
+	}
+	if runs != 3 {
+		t.Errorf("Loop() ran %d times, want 3", runs)
+	}
+}